@@ -154,6 +154,16 @@ func (a *App) buildInstance() (*registry.ServiceInstance, error) {
 	}
 	if len(endpoints) == 0 {
 		for _, srv := range a.opts.servers {
+			if r, ok := srv.(transport.MultiEndpointer); ok {
+				es, err := r.Endpoints()
+				if err != nil {
+					return nil, err
+				}
+				for _, e := range es {
+					endpoints = append(endpoints, e.String())
+				}
+				continue
+			}
 			if r, ok := srv.(transport.Endpointer); ok {
 				e, err := r.Endpoint()
 				if err != nil {
@@ -5,11 +5,13 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"google.golang.org/protobuf/reflect/protoreflect"
 
 	"google.golang.org/genproto/googleapis/api/annotations"
 	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
@@ -18,6 +20,14 @@ const (
 	contextPackage       = protogen.GoImportPath("context")
 	transportHTTPPackage = protogen.GoImportPath("github.com/go-kratos/kratos/v2/transport/http")
 	bindingPackage       = protogen.GoImportPath("github.com/go-kratos/kratos/v2/transport/http/binding")
+	grpcPackage          = protogen.GoImportPath("google.golang.org/grpc")
+	timePackage          = protogen.GoImportPath("time")
+)
+
+// the field numbers of the route options declared in kratos/http.proto.
+const (
+	httpTimeoutField     = 1111
+	httpMaxBodySizeField = 1112
 )
 
 var methodSets = make(map[string]int)
@@ -74,10 +84,14 @@ func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.Generated
 		Metadata:    file.Desc.Path(),
 	}
 	for _, method := range service.Methods {
-		if method.Desc.IsStreamingClient() || method.Desc.IsStreamingServer() {
+		if skipMethod(method) {
 			continue
 		}
 		rule, ok := proto.GetExtension(method.Desc.Options(), annotations.E_Http).(*annotations.HttpRule)
+		if method.Desc.IsStreamingServer() && (rule == nil || !ok) {
+			// server-streaming methods are only served with an explicit HTTP rule.
+			continue
+		}
 		if rule != nil && ok {
 			for _, bind := range rule.AdditionalBindings {
 				sd.Methods = append(sd.Methods, buildHTTPRule(g, method, bind))
@@ -96,7 +110,7 @@ func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.Generated
 func hasHTTPRule(services []*protogen.Service) bool {
 	for _, service := range services {
 		for _, method := range service.Methods {
-			if method.Desc.IsStreamingClient() || method.Desc.IsStreamingServer() {
+			if skipMethod(method) {
 				continue
 			}
 			rule, ok := proto.GetExtension(method.Desc.Options(), annotations.E_Http).(*annotations.HttpRule)
@@ -108,6 +122,15 @@ func hasHTTPRule(services []*protogen.Service) bool {
 	return false
 }
 
+// skipMethod reports whether the method is not served over HTTP, server-streaming
+// methods are served as Server-Sent Events when the sse option is enabled.
+func skipMethod(method *protogen.Method) bool {
+	if method.Desc.IsStreamingClient() {
+		return true
+	}
+	return method.Desc.IsStreamingServer() && !*sse
+}
+
 func buildHTTPRule(g *protogen.GeneratedFile, m *protogen.Method, rule *annotations.HttpRule) *methodDesc {
 	var (
 		path         string
@@ -197,7 +220,7 @@ func buildMethodDesc(g *protogen.GeneratedFile, m *protogen.Method, method, path
 			}
 		}
 	}
-	return &methodDesc{
+	md := &methodDesc{
 		Name:         m.GoName,
 		OriginalName: string(m.Desc.Name()),
 		Num:          methodSets[m.GoName],
@@ -207,6 +230,87 @@ func buildMethodDesc(g *protogen.GeneratedFile, m *protogen.Method, method, path
 		Method:       method,
 		HasVars:      len(vars) > 0,
 	}
+	timeout, maxBodySize := routeOptions(m)
+	if timeout != 0 {
+		md.Timeout = durationExpr(g, timeout)
+	}
+	md.MaxBodySize = maxBodySize
+	if m.Desc.IsStreamingServer() {
+		// the event streams are long-lived, so the server timeout is not applied
+		// unless the timeout of the method is set.
+		if md.Timeout == "" {
+			md.Timeout = "-1"
+		}
+		md.ServerStreaming = true
+		md.ServerStream = g.QualifiedGoIdent(grpcPackage.Ident("ServerStream"))
+	}
+	return md
+}
+
+// routeOptions returns the kratos.http_timeout and kratos.http_max_body_size
+// options of the method, they are read from the unknown fields so that the
+// generator doesn't depend on the kratos module.
+func routeOptions(m *protogen.Method) (timeout time.Duration, maxBodySize int64) {
+	opts, ok := m.Desc.Options().(*descriptorpb.MethodOptions)
+	if !ok || opts == nil {
+		return
+	}
+	b := opts.ProtoReflect().GetUnknown()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return
+		}
+		b = b[n:]
+		switch {
+		case num == httpTimeoutField && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return
+			}
+			d, err := time.ParseDuration(string(v))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\u001B[31mERROR\u001B[m: The http_timeout '%s' of method '%s' is invalid: %v\n", v, m.Desc.FullName(), err)
+				os.Exit(2)
+			}
+			timeout = d
+			b = b[n:]
+		case num == httpMaxBodySizeField && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return
+			}
+			maxBodySize = int64(v)
+			b = b[n:]
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return
+			}
+			b = b[n:]
+		}
+	}
+	return
+}
+
+// durationExpr returns the Go expression of the duration, e.g. 5 * time.Second.
+func durationExpr(g *protogen.GeneratedFile, d time.Duration) string {
+	units := []struct {
+		unit time.Duration
+		name string
+	}{
+		{time.Hour, "Hour"},
+		{time.Minute, "Minute"},
+		{time.Second, "Second"},
+		{time.Millisecond, "Millisecond"},
+		{time.Microsecond, "Microsecond"},
+	}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			return fmt.Sprintf("%d * %s", d/u.unit, g.QualifiedGoIdent(timePackage.Ident(u.name)))
+		}
+	}
+	return fmt.Sprintf("%d * %s", d, g.QualifiedGoIdent(timePackage.Ident("Nanosecond")))
 }
 
 func buildPathVars(path string) (res map[string]*string) {
@@ -2,7 +2,14 @@ package main
 
 import (
 	"reflect"
+	"strings"
 	"testing"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
 )
 
 func TestNoParameters(t *testing.T) {
@@ -85,3 +92,56 @@ func TestIterationMiddle(t *testing.T) {
 		t.Fatal(`replacePath("message.name", "messages/*", path) should be "/test/{message.name:messages/.*}/books"`)
 	}
 }
+
+func TestServerStreaming(t *testing.T) {
+	*sse = true
+	defer func() { *sse = false }()
+	opts := &descriptorpb.MethodOptions{}
+	proto.SetExtension(opts, annotations.E_Http, &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/watch"}})
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("helloworld.proto"),
+		Package: proto.String("helloworld"),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/helloworld;helloworld")},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("WatchRequest")},
+			{Name: proto.String("WatchReply")},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Greeter"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:            proto.String("Watch"),
+				InputType:       proto.String(".helloworld.WatchRequest"),
+				OutputType:      proto.String(".helloworld.WatchReply"),
+				ServerStreaming: proto.Bool(true),
+				Options:         opts,
+			}},
+		}},
+	}
+	gen, err := protogen.Options{}.New(&pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{file.GetName()},
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{file},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := generateFile(gen, gen.Files[0], true).Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		// the event stream is not limited by the server timeout.
+		`r.Timeout(-1).GET("/watch", _Greeter_Watch0_HTTP_Handler(srv))`,
+		"Watch(*WatchRequest, Greeter_WatchServer) error",
+		"stream := http.SSE(ctx)",
+		"return stream.Close(err)",
+		"func (x *_Greeter_Watch_HTTP_Stream) Send(m *WatchReply) error {",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("expected %q in the generated code:\n%s", want, content)
+		}
+	}
+	if strings.Contains(string(content), "Watch(ctx context.Context") {
+		t.Errorf("expected no client method of the server-streaming method:\n%s", content)
+	}
+}
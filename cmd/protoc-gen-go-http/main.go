@@ -11,6 +11,7 @@ import (
 var (
 	showVersion = flag.Bool("version", false, "print the version and exit")
 	omitempty   = flag.Bool("omitempty", true, "omit if google.api is empty")
+	sse         = flag.Bool("sse", false, "serve server-streaming methods as Server-Sent Events")
 )
 
 func main() {
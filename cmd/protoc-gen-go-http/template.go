@@ -16,14 +16,18 @@ const Operation{{$svrType}}{{.OriginalName}} = "/{{$svrName}}/{{.OriginalName}}"
 
 type {{.ServiceType}}HTTPServer interface {
 {{- range .MethodSets}}
+	{{- if .ServerStreaming}}
+	{{.Name}}(*{{.Request}}, {{$svrType}}_{{.Name}}Server) error
+	{{- else}}
 	{{.Name}}(context.Context, *{{.Request}}) (*{{.Reply}}, error)
+	{{- end}}
 {{- end}}
 }
 
 func Register{{.ServiceType}}HTTPServer(s *http.Server, srv {{.ServiceType}}HTTPServer) {
 	r := s.Route("/")
 	{{- range .Methods}}
-	r.{{.Method}}("{{.Path}}", _{{$svrType}}_{{.Name}}{{.Num}}_HTTP_Handler(srv))
+	r{{if .Timeout}}.Timeout({{.Timeout}}){{end}}{{if .MaxBodySize}}.MaxBodySize({{.MaxBodySize}}){{end}}.{{.Method}}("{{.Path}}", _{{$svrType}}_{{.Name}}{{.Num}}_HTTP_Handler(srv))
 	{{- end}}
 }
 
@@ -52,6 +56,14 @@ func _{{$svrType}}_{{.Name}}{{.Num}}_HTTP_Handler(srv {{$svrType}}HTTPServer) fu
 		}
 		{{- end}}
 		http.SetOperation(ctx,Operation{{$svrType}}{{.OriginalName}})
+		{{- if .ServerStreaming}}
+		stream := http.SSE(ctx)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, srv.{{.Name}}(req.(*{{.Request}}), &_{{$svrType}}_{{.Name}}_HTTP_Stream{stream.ServerStream(ctx)})
+		})
+		_, err := h(ctx, &in)
+		return stream.Close(err)
+		{{- else}}
 		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
 			return srv.{{.Name}}(ctx, req.(*{{.Request}}))
 		})
@@ -61,13 +73,28 @@ func _{{$svrType}}_{{.Name}}{{.Num}}_HTTP_Handler(srv {{$svrType}}HTTPServer) fu
 		}
 		reply := out.(*{{.Reply}})
 		return ctx.Result(200, reply{{.ResponseBody}})
+		{{- end}}
 	}
 }
 {{end}}
 
+{{- range .MethodSets}}
+{{- if .ServerStreaming}}
+type _{{$svrType}}_{{.Name}}_HTTP_Stream struct {
+	{{.ServerStream}}
+}
+
+func (x *_{{$svrType}}_{{.Name}}_HTTP_Stream) Send(m *{{.Reply}}) error {
+	return x.ServerStream.SendMsg(m)
+}
+{{end}}
+{{- end}}
+
 type {{.ServiceType}}HTTPClient interface {
 {{- range .MethodSets}}
+	{{- if not .ServerStreaming}}
 	{{.Name}}(ctx context.Context, req *{{.Request}}, opts ...http.CallOption) (rsp *{{.Reply}}, err error) 
+	{{- end}}
 {{- end}}
 }
 	
@@ -80,6 +107,7 @@ func New{{.ServiceType}}HTTPClient (client *http.Client) {{.ServiceType}}HTTPCli
 }
 
 {{range .MethodSets}}
+{{- if not .ServerStreaming}}
 func (c *{{$svrType}}HTTPClientImpl) {{.Name}}(ctx context.Context, in *{{.Request}}, opts ...http.CallOption) (*{{.Reply}}, error) {
 	var out {{.Reply}}
 	pattern := "{{.Path}}"
@@ -96,6 +124,7 @@ func (c *{{$svrType}}HTTPClientImpl) {{.Name}}(ctx context.Context, in *{{.Reque
 	}
 	return &out, err
 }
+{{- end}}
 {{end}}
 `
 
@@ -121,6 +150,12 @@ type methodDesc struct {
 	HasBody      bool
 	Body         string
 	ResponseBody string
+	// route options
+	Timeout     string
+	MaxBodySize int64
+	// server-streaming methods are served as Server-Sent Events
+	ServerStreaming bool
+	ServerStream    string
 }
 
 func (s *serviceDesc) execute() string {
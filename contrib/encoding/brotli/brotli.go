@@ -0,0 +1,31 @@
+package brotli
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+)
+
+// Name is the name registered for the brotli compressor.
+const Name = "br"
+
+func init() {
+	encoding.RegisterCompressor(compressor{})
+}
+
+// compressor is a Compressor implementation with brotli.
+type compressor struct{}
+
+func (compressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return brotli.NewWriter(w), nil
+}
+
+func (compressor) Decompress(r io.Reader) (io.Reader, error) {
+	return brotli.NewReader(r), nil
+}
+
+func (compressor) Name() string {
+	return Name
+}
@@ -0,0 +1,40 @@
+package brotli
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+)
+
+func TestCompressor(t *testing.T) {
+	c := encoding.GetCompressor(Name)
+	if c == nil {
+		t.Fatal("brotli compressor is not registered")
+	}
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		w, err := c.Compress(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write([]byte("kratos")); err != nil {
+			t.Fatal(err)
+		}
+		if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		r, err := c.Decompress(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "kratos" {
+			t.Errorf("expected kratos, got %q", data)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/go-kratos/kratos/contrib/encoding/brotli/v2
+
+go 1.16
+
+require (
+	github.com/andybalholm/brotli v1.0.4
+	github.com/go-kratos/kratos/v2 v2.5.0
+)
+
+replace github.com/go-kratos/kratos/v2 => ../../../
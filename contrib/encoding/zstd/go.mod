@@ -0,0 +1,11 @@
+module github.com/go-kratos/kratos/contrib/encoding/zstd/v2
+
+// klauspost/compress, which implements zstd, requires go 1.22 unlike the other modules.
+go 1.22
+
+require (
+	github.com/go-kratos/kratos/v2 v2.5.0
+	github.com/klauspost/compress v1.18.0
+)
+
+replace github.com/go-kratos/kratos/v2 => ../../../
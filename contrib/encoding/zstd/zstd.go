@@ -0,0 +1,35 @@
+package zstd
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+)
+
+// Name is the name registered for the zstd compressor.
+const Name = "zstd"
+
+func init() {
+	encoding.RegisterCompressor(compressor{})
+}
+
+// compressor is a Compressor implementation with zstd.
+type compressor struct{}
+
+func (compressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+func (compressor) Decompress(r io.Reader) (io.Reader, error) {
+	d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
+
+func (compressor) Name() string {
+	return Name
+}
@@ -0,0 +1,13 @@
+package http3
+
+import (
+	"crypto/tls"
+
+	h3 "github.com/quic-go/quic-go/http3"
+)
+
+// NewTransport returns an experimental HTTP/3 transport, which is used by the
+// HTTP client with khttp.WithTransport and khttp.WithTLSConfig.
+func NewTransport(c *tls.Config) *h3.Transport {
+	return &h3.Transport{TLSClientConfig: c}
+}
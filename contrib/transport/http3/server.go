@@ -0,0 +1,126 @@
+package http3
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go"
+	h3 "github.com/quic-go/quic-go/http3"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+)
+
+var _ transport.Server = (*Server)(nil)
+
+// ServerOption is an HTTP/3 server option.
+type ServerOption func(*Server)
+
+// Address with the UDP address of the server.
+func Address(addr string) ServerOption {
+	return func(s *Server) {
+		s.address = addr
+	}
+}
+
+// TLSConfig with TLS config, which is required by HTTP/3.
+func TLSConfig(c *tls.Config) ServerOption {
+	return func(s *Server) {
+		s.TLSConfig = c
+	}
+}
+
+// QUICConfig with QUIC config.
+func QUICConfig(c *quic.Config) ServerOption {
+	return func(s *Server) {
+		s.Server.QUICConfig = c
+	}
+}
+
+// PacketConn with server packet conn.
+func PacketConn(conn net.PacketConn) ServerOption {
+	return func(s *Server) {
+		s.conn = conn
+	}
+}
+
+// Server is an experimental HTTP/3 (QUIC) server, which serves the routes,
+// filters and middleware of an HTTP server.
+type Server struct {
+	*h3.Server
+	conn    net.PacketConn
+	address string
+}
+
+// NewServer creates an HTTP/3 server which serves the HTTP server.
+func NewServer(srv *khttp.Server, opts ...ServerOption) *Server {
+	s := &Server{
+		Server:  &h3.Server{Handler: srv},
+		address: ":0",
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// Addr returns the UDP address of the server.
+func (s *Server) Addr() (net.Addr, error) {
+	if err := s.listen(); err != nil {
+		return nil, err
+	}
+	return s.conn.LocalAddr(), nil
+}
+
+// Start start the HTTP/3 server.
+func (s *Server) Start(ctx context.Context) error {
+	if s.TLSConfig == nil {
+		return errors.New("http3: TLS config is required")
+	}
+	if err := s.listen(); err != nil {
+		return err
+	}
+	log.Infof("[HTTP3] server listening on: %s", s.conn.LocalAddr().String())
+	err := s.Serve(s.conn)
+	if errors.Is(err, http.ErrServerClosed) || errors.Is(err, quic.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Stop stop the HTTP/3 server.
+func (s *Server) Stop(ctx context.Context) error {
+	log.Info("[HTTP3] server stopping")
+	return s.Shutdown(ctx)
+}
+
+func (s *Server) listen() error {
+	if s.conn != nil {
+		return nil
+	}
+	conn, err := net.ListenPacket("udp", s.address)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+// AltSvc returns a filter of the HTTP server which advertises the HTTP/3
+// server on the UDP port by the Alt-Svc header, so that browsers switch to it.
+func AltSvc(port int) khttp.FilterFunc {
+	value := fmt.Sprintf(`h3=":%d"; ma=2592000`, port)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.ProtoMajor < 3 {
+				w.Header().Add("Alt-Svc", value)
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package http3
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+)
+
+func newCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestServer(t *testing.T) {
+	cert, pool := newCert(t)
+	hs := khttp.NewServer()
+	hs.Route("/").GET("/proto", func(ctx khttp.Context) error {
+		return ctx.String(http.StatusOK, ctx.Request().Proto)
+	})
+	srv := NewServer(hs, Address("127.0.0.1:0"), TLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}}))
+	addr, err := srv.Addr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		if err := srv.Start(context.Background()); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer func() { _ = srv.Stop(context.Background()) }()
+	time.Sleep(100 * time.Millisecond)
+
+	tr := NewTransport(&tls.Config{RootCAs: pool})
+	defer tr.Close()
+	client, err := khttp.NewClient(context.Background(),
+		khttp.WithEndpoint(addr.String()),
+		khttp.WithTransport(tr),
+		khttp.WithTLSConfig(&tls.Config{RootCAs: pool}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://"+addr.String()+"/proto", nil)
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != "HTTP/3.0" {
+		t.Errorf("expected HTTP/3.0, got %q", body)
+	}
+}
+
+func TestAltSvc(t *testing.T) {
+	h := AltSvc(8443)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := w.Header().Get("Alt-Svc"); got != `h3=":8443"; ma=2592000` {
+		t.Errorf("unexpected Alt-Svc: %s", got)
+	}
+}
@@ -0,0 +1,46 @@
+package encoding
+
+import (
+	"io"
+	"strings"
+)
+
+// Compressor defines the interface Transport uses to compress and decompress
+// message bodies. Note that implementations of this interface must be thread
+// safe; a Compressor's methods can be called from concurrent goroutines.
+type Compressor interface {
+	// Compress writes the data written to wc to w after compressing it. If an
+	// error occurs while initializing the compressor, that error is returned
+	// instead.
+	Compress(w io.Writer) (io.WriteCloser, error)
+	// Decompress reads data from r, decompresses it, and provides the
+	// uncompressed data via the returned io.Reader. If an error occurs while
+	// initializing the decompressor, that error is returned instead.
+	Decompress(r io.Reader) (io.Reader, error)
+	// Name returns the content-coding of the Compressor implementation, e.g.
+	// gzip. The result is used in the Content-Encoding and Accept-Encoding
+	// headers, and must be static; the result cannot change between calls.
+	Name() string
+}
+
+var registeredCompressors = make(map[string]Compressor)
+
+// RegisterCompressor registers the provided Compressor for use with all
+// Transport clients and servers.
+func RegisterCompressor(c Compressor) {
+	if c == nil {
+		panic("cannot register a nil Compressor")
+	}
+	if c.Name() == "" {
+		panic("cannot register Compressor with empty string result for Name()")
+	}
+	registeredCompressors[strings.ToLower(c.Name())] = c
+}
+
+// GetCompressor gets a registered Compressor by content-coding, or nil if no
+// Compressor is registered for the content-coding.
+//
+// The content-coding is expected to be lowercase.
+func GetCompressor(contentCoding string) Compressor {
+	return registeredCompressors[contentCoding]
+}
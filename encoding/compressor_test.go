@@ -0,0 +1,64 @@
+package encoding_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/encoding/deflate"
+	"github.com/go-kratos/kratos/v2/encoding/gzip"
+)
+
+func compress(t *testing.T, c encoding.Compressor, data []byte) []byte {
+	var buf bytes.Buffer
+	w, err := c.Compress(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decompress(c encoding.Compressor, data []byte) ([]byte, error) {
+	r, err := c.Decompress(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestCompressor(t *testing.T) {
+	data := []byte(strings.Repeat("kratos ", 128))
+	for _, name := range []string{gzip.Name, deflate.Name} {
+		t.Run(name, func(t *testing.T) {
+			c := encoding.GetCompressor(name)
+			if c == nil {
+				t.Fatalf("%s compressor is not registered", name)
+			}
+			// the second round trip uses the pooled writer.
+			for i := 0; i < 2; i++ {
+				got, err := decompress(c, compress(t, c, data))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, data) {
+					t.Errorf("expected %q, got %q", data, got)
+				}
+			}
+			compressed := compress(t, c, data)
+			if _, err := decompress(c, compressed[:len(compressed)/2]); err == nil {
+				t.Error("expected an error of the truncated stream")
+			}
+			if _, err := decompress(c, []byte("not a compressed stream")); err == nil {
+				t.Error("expected an error of the corrupt stream")
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package deflate
+
+import (
+	"compress/zlib"
+	"io"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+)
+
+// Name is the name registered for the deflate compressor.
+const Name = "deflate"
+
+func init() {
+	encoding.RegisterCompressor(compressor{})
+}
+
+// compressor is a Compressor implementation with deflate, which is the
+// zlib format in HTTP content-coding.
+type compressor struct{}
+
+func (compressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return zlib.NewWriter(w), nil
+}
+
+func (compressor) Decompress(r io.Reader) (io.Reader, error) {
+	return zlib.NewReader(r)
+}
+
+func (compressor) Name() string {
+	return Name
+}
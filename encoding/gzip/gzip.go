@@ -0,0 +1,47 @@
+package gzip
+
+import (
+	"compress/gzip"
+	"io"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+)
+
+// Name is the name registered for the gzip compressor.
+const Name = "gzip"
+
+func init() {
+	encoding.RegisterCompressor(&compressor{})
+}
+
+// compressor is a Compressor implementation with gzip, the writers are pooled.
+type compressor struct {
+	pool sync.Pool
+}
+
+func (c *compressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	if z, ok := c.pool.Get().(*gzip.Writer); ok {
+		z.Reset(w)
+		return &writer{Writer: z, pool: &c.pool}, nil
+	}
+	return &writer{Writer: gzip.NewWriter(w), pool: &c.pool}, nil
+}
+
+func (c *compressor) Decompress(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+func (c *compressor) Name() string {
+	return Name
+}
+
+type writer struct {
+	*gzip.Writer
+	pool *sync.Pool
+}
+
+func (w *writer) Close() error {
+	defer w.pool.Put(w.Writer)
+	return w.Writer.Close()
+}
@@ -0,0 +1,30 @@
+// Package reply copies the reply returned by the client middleware into the reply
+// of the caller, so that a middleware can answer a call without the transport,
+// e.g. from a cache.
+package reply
+
+import (
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Copy copies src into dst if they are different values of the same type,
+// the protobuf messages are deep copied and the other pointers are shallow copied.
+func Copy(dst, src interface{}) {
+	if dst == nil || src == nil || dst == src {
+		return
+	}
+	if dm, ok := dst.(proto.Message); ok {
+		if sm, ok := src.(proto.Message); ok && dm.ProtoReflect().Descriptor() == sm.ProtoReflect().Descriptor() {
+			proto.Reset(dm)
+			proto.Merge(dm, sm)
+		}
+		return
+	}
+	dv, sv := reflect.ValueOf(dst), reflect.ValueOf(src)
+	if dv.Kind() != reflect.Ptr || dv.Type() != sv.Type() || dv.IsNil() || sv.IsNil() {
+		return
+	}
+	dv.Elem().Set(sv.Elem())
+}
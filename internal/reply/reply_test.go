@@ -0,0 +1,38 @@
+package reply
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCopy(t *testing.T) {
+	src := wrapperspb.String("kratos")
+	dst := wrapperspb.String("stale")
+	Copy(dst, src)
+	if dst.Value != "kratos" {
+		t.Errorf("expected kratos, got %s", dst.Value)
+	}
+	src.Value = "changed"
+	if dst.Value != "kratos" {
+		t.Error("expected the message to be deep copied")
+	}
+	// the messages of different types are not copied.
+	i := wrapperspb.Int32(1)
+	Copy(i, src)
+	if i.Value != 1 {
+		t.Errorf("unexpected value: %d", i.Value)
+	}
+
+	m := map[string]string{}
+	Copy(&m, &map[string]string{"name": "kratos"})
+	if m["name"] != "kratos" {
+		t.Errorf("unexpected map: %v", m)
+	}
+	var s string
+	Copy(&s, &m)
+	Copy(nil, &m)
+	if s != "" {
+		t.Errorf("unexpected string: %s", s)
+	}
+}
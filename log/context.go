@@ -0,0 +1,28 @@
+package log
+
+import "context"
+
+type fieldsKey struct{}
+
+// NewContext returns a new Context that carries request-scoped log fields.
+// The fields are appended to the fields already carried by ctx, and values
+// may be Valuer which are resolved each time a log is written.
+func NewContext(ctx context.Context, kv ...interface{}) context.Context {
+	if len(kv) == 0 {
+		return ctx
+	}
+	prev, _ := FromContext(ctx)
+	kvs := make([]interface{}, 0, len(prev)+len(kv))
+	kvs = append(kvs, prev...)
+	kvs = append(kvs, kv...)
+	return context.WithValue(ctx, fieldsKey{}, kvs)
+}
+
+// FromContext returns the request-scoped log fields in ctx if it exists.
+func FromContext(ctx context.Context) (kv []interface{}, ok bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	kv, ok = ctx.Value(fieldsKey{}).([]interface{})
+	return
+}
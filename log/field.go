@@ -0,0 +1,169 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DefaultErrorKey default error key of Err field.
+var DefaultErrorKey = "error"
+
+// Field is a typed key-value pair, it can be passed to Log in place of a key
+// and its value, e.g. logger.Log(LevelInfo, String("user", "kratos"), "key", "value").
+// The field is expanded into the key and value once, before the keyvals reach
+// the underlying logger.
+type Field struct {
+	Key   string
+	value interface{}
+}
+
+// String returns a string field.
+func String(key, value string) Field {
+	return Field{Key: key, value: value}
+}
+
+// Int returns an int field.
+func Int(key string, value int) Field {
+	return Int64(key, int64(value))
+}
+
+// Int64 returns an int64 field.
+func Int64(key string, value int64) Field {
+	return Field{Key: key, value: value}
+}
+
+// Float64 returns a float64 field.
+func Float64(key string, value float64) Field {
+	return Field{Key: key, value: value}
+}
+
+// Bool returns a bool field.
+func Bool(key string, value bool) Field {
+	return Field{Key: key, value: value}
+}
+
+// Duration returns a time.Duration field.
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, value: value}
+}
+
+// Err returns an error field with the DefaultErrorKey.
+func Err(err error) Field {
+	return Any(DefaultErrorKey, err)
+}
+
+// Any returns a field with an arbitrary value.
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, value: value}
+}
+
+// Group returns a named group of fields, which is rendered as a nested
+// object by JSON loggers and as dotted keys (group.key) by text loggers.
+func Group(key string, fields ...Field) Field {
+	return Field{Key: key, value: GroupValue(fields)}
+}
+
+// Value returns the value of the field, the value of a group is GroupValue.
+func (f Field) Value() interface{} {
+	if err, ok := f.value.(error); ok && err != nil {
+		return err.Error()
+	}
+	return f.value
+}
+
+// GroupValue is the value of a group field.
+type GroupValue []Field
+
+// MarshalJSON renders the group as a JSON object that keeps the field order.
+func (g GroupValue) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteByte('{')
+	for i, f := range g {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(f.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(f.Value())
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// String renders the group as space separated key=value pairs with dotted keys.
+func (g GroupValue) String() string {
+	buf := bytes.NewBuffer(nil)
+	g.flatten("", func(key string, value interface{}) {
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		_, _ = fmt.Fprintf(buf, "%s=%v", key, value)
+	})
+	return buf.String()
+}
+
+// flatten calls fn for each field of the group with dotted keys.
+func (g GroupValue) flatten(prefix string, fn func(key string, value interface{})) {
+	for _, f := range g {
+		key := f.Key
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if sub, ok := f.value.(GroupValue); ok {
+			sub.flatten(key, fn)
+			continue
+		}
+		fn(key, f.Value())
+	}
+}
+
+// logFields logs the keyvals by l, the fields are expanded by the logger returned
+// by With, so they are passed through to it, and expanded here for the others.
+func logFields(l Logger, level Level, keyvals []interface{}) error {
+	if a, ok := l.(*loggerAppliance); ok {
+		l = a.Logger
+	}
+	if _, ok := l.(*logger); !ok {
+		keyvals = expandFields(keyvals)
+	}
+	return l.Log(level, keyvals...)
+}
+
+// expandFields returns the keyvals with the fields expanded into key-value pairs,
+// so that loggers which do not know about Field keep working.
+func expandFields(keyvals []interface{}) []interface{} {
+	i := 0
+	for ; i < len(keyvals); i += 2 {
+		if _, ok := keyvals[i].(Field); ok {
+			break
+		}
+	}
+	if i >= len(keyvals) {
+		return keyvals
+	}
+	kvs := make([]interface{}, 0, len(keyvals)+8)
+	kvs = append(kvs, keyvals[:i]...)
+	for i < len(keyvals) {
+		if f, ok := keyvals[i].(Field); ok {
+			kvs = append(kvs, f.Key, f.Value())
+			i++
+			continue
+		}
+		kvs = append(kvs, keyvals[i])
+		if i+1 < len(keyvals) {
+			kvs = append(kvs, keyvals[i+1])
+		}
+		i += 2
+	}
+	return kvs
+}
@@ -0,0 +1,102 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFieldValue(t *testing.T) {
+	tests := []struct {
+		field Field
+		want  interface{}
+	}{
+		{String("k", "v"), "v"},
+		{Int("k", -1), int64(-1)},
+		{Float64("k", 1.5), 1.5},
+		{Bool("k", true), true},
+		{Duration("k", time.Second), time.Second},
+		{Err(errors.New("failed")), "failed"},
+		{Any("k", 1), 1},
+	}
+	for _, test := range tests {
+		if test.field.Value() != test.want {
+			t.Errorf("expected value %v got %v", test.want, test.field.Value())
+		}
+	}
+	if Err(nil).Key != DefaultErrorKey {
+		t.Errorf("expected error key %s", DefaultErrorKey)
+	}
+}
+
+func TestFieldText(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	logger := NewHelper(With(NewStdLogger(buf), String("service", "kratos")))
+	logger.Infow(
+		"key", "value",
+		Group("http", String("method", "GET"), Int("status", 200), Group("peer", String("addr", "127.0.0.1"))),
+		Duration("latency", time.Second),
+	)
+	want := "INFO service=kratos key=value http.method=GET http.status=200 http.peer.addr=127.0.0.1 latency=1s\n"
+	if buf.String() != want {
+		t.Errorf("expected %q got %q", want, buf.String())
+	}
+}
+
+func TestFieldJSON(t *testing.T) {
+	group := Group("http", String("method", "GET"), Int("status", 200), Group("peer", String("addr", "127.0.0.1")))
+	b, err := json.Marshal(map[string]interface{}{group.Key: group.Value()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"http":{"method":"GET","status":200,"peer":{"addr":"127.0.0.1"}}}`
+	if string(b) != want {
+		t.Errorf("expected %s got %s", want, b)
+	}
+	if s := group.Value().(GroupValue).String(); !strings.Contains(s, "peer.addr=127.0.0.1") {
+		t.Errorf("unexpected group string %s", s)
+	}
+}
+
+func TestExpandFields(t *testing.T) {
+	kvs := []interface{}{"a", 1, "b", 2}
+	if got := expandFields(kvs); &got[0] != &kvs[0] {
+		t.Errorf("expected keyvals without fields unchanged")
+	}
+	got := expandFields([]interface{}{"a", 1, String("b", "2"), "c", 3})
+	want := []interface{}{"a", 1, "b", "2", "c", 3}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v got %v", want, got)
+		}
+	}
+}
+
+type countingError struct{ n *int }
+
+func (e countingError) Error() string {
+	*e.n++
+	return "failed"
+}
+
+func TestFieldExpandedOnce(t *testing.T) {
+	var n int
+	buf := bytes.NewBuffer(nil)
+	for _, logger := range []Logger{NewStdLogger(buf), With(NewStdLogger(buf), "service", "kratos")} {
+		n = 0
+		buf.Reset()
+		NewHelper(logger).Errorw(Err(countingError{&n}))
+		if n != 1 {
+			t.Errorf("expected the field expanded once, got %d", n)
+		}
+		if !strings.Contains(buf.String(), "error=failed") {
+			t.Errorf("unexpected output %q", buf.String())
+		}
+	}
+}
@@ -40,7 +40,7 @@ func GetLogger() Logger {
 
 // Log Print log by level and keyvals.
 func Log(level Level, keyvals ...interface{}) {
-	_ = global.Log(level, keyvals...)
+	_ = logFields(global, level, keyvals)
 }
 
 // Context with context logger.
@@ -60,7 +60,7 @@ func Debugf(format string, a ...interface{}) {
 
 // Debugw logs a message at debug level.
 func Debugw(keyvals ...interface{}) {
-	_ = global.Log(LevelDebug, keyvals...)
+	_ = logFields(global, LevelDebug, keyvals)
 }
 
 // Info logs a message at info level.
@@ -75,7 +75,7 @@ func Infof(format string, a ...interface{}) {
 
 // Infow logs a message at info level.
 func Infow(keyvals ...interface{}) {
-	_ = global.Log(LevelInfo, keyvals...)
+	_ = logFields(global, LevelInfo, keyvals)
 }
 
 // Warn logs a message at warn level.
@@ -90,7 +90,7 @@ func Warnf(format string, a ...interface{}) {
 
 // Warnw logs a message at warnf level.
 func Warnw(keyvals ...interface{}) {
-	_ = global.Log(LevelWarn, keyvals...)
+	_ = logFields(global, LevelWarn, keyvals)
 }
 
 // Error logs a message at error level.
@@ -105,7 +105,7 @@ func Errorf(format string, a ...interface{}) {
 
 // Errorw logs a message at error level.
 func Errorw(keyvals ...interface{}) {
-	_ = global.Log(LevelError, keyvals...)
+	_ = logFields(global, LevelError, keyvals)
 }
 
 // Fatal logs a message at fatal level.
@@ -122,6 +122,6 @@ func Fatalf(format string, a ...interface{}) {
 
 // Fatalw logs a message at fatal level.
 func Fatalw(keyvals ...interface{}) {
-	_ = global.Log(LevelFatal, keyvals...)
+	_ = logFields(global, LevelFatal, keyvals)
 	os.Exit(1)
 }
@@ -48,7 +48,7 @@ func (h *Helper) WithContext(ctx context.Context) *Helper {
 
 // Log Print log by level and keyvals.
 func (h *Helper) Log(level Level, keyvals ...interface{}) {
-	_ = h.logger.Log(level, keyvals...)
+	_ = logFields(h.logger, level, keyvals)
 }
 
 // Debug logs a message at debug level.
@@ -63,7 +63,7 @@ func (h *Helper) Debugf(format string, a ...interface{}) {
 
 // Debugw logs a message at debug level.
 func (h *Helper) Debugw(keyvals ...interface{}) {
-	_ = h.logger.Log(LevelDebug, keyvals...)
+	_ = logFields(h.logger, LevelDebug, keyvals)
 }
 
 // Info logs a message at info level.
@@ -78,7 +78,7 @@ func (h *Helper) Infof(format string, a ...interface{}) {
 
 // Infow logs a message at info level.
 func (h *Helper) Infow(keyvals ...interface{}) {
-	_ = h.logger.Log(LevelInfo, keyvals...)
+	_ = logFields(h.logger, LevelInfo, keyvals)
 }
 
 // Warn logs a message at warn level.
@@ -93,7 +93,7 @@ func (h *Helper) Warnf(format string, a ...interface{}) {
 
 // Warnw logs a message at warnf level.
 func (h *Helper) Warnw(keyvals ...interface{}) {
-	_ = h.logger.Log(LevelWarn, keyvals...)
+	_ = logFields(h.logger, LevelWarn, keyvals)
 }
 
 // Error logs a message at error level.
@@ -108,7 +108,7 @@ func (h *Helper) Errorf(format string, a ...interface{}) {
 
 // Errorw logs a message at error level.
 func (h *Helper) Errorw(keyvals ...interface{}) {
-	_ = h.logger.Log(LevelError, keyvals...)
+	_ = logFields(h.logger, LevelError, keyvals)
 }
 
 // Fatal logs a message at fatal level.
@@ -125,6 +125,6 @@ func (h *Helper) Fatalf(format string, a ...interface{}) {
 
 // Fatalw logs a message at fatal level.
 func (h *Helper) Fatalw(keyvals ...interface{}) {
-	_ = h.logger.Log(LevelFatal, keyvals...)
+	_ = logFields(h.logger, LevelFatal, keyvals)
 	os.Exit(1)
 }
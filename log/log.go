@@ -21,12 +21,17 @@ type logger struct {
 }
 
 func (c *logger) Log(level Level, keyvals ...interface{}) error {
-	kvs := make([]interface{}, 0, len(c.prefix)+len(keyvals))
+	fields, _ := FromContext(c.ctx)
+	kvs := make([]interface{}, 0, len(c.prefix)+len(fields)+len(keyvals))
 	kvs = append(kvs, c.prefix...)
 	if c.hasValuer {
 		bindValues(c.ctx, kvs)
 	}
-	kvs = append(kvs, keyvals...)
+	if len(fields) > 0 {
+		kvs = append(kvs, fields...)
+		bindValues(c.ctx, kvs[len(c.prefix):])
+	}
+	kvs = append(kvs, expandFields(keyvals)...)
 	if err := c.logger.Log(level, kvs...); err != nil {
 		return err
 	}
@@ -35,6 +40,7 @@ func (c *logger) Log(level Level, keyvals ...interface{}) error {
 
 // With with logger fields.
 func With(l Logger, kv ...interface{}) Logger {
+	kv = expandFields(kv)
 	c, ok := l.(*logger)
 	if !ok {
 		return &logger{logger: l, prefix: kv, hasValuer: containsValuer(kv), ctx: context.Background()}
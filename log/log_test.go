@@ -15,3 +15,32 @@ func TestInfo(t *testing.T) {
 func TestWithContext(t *testing.T) {
 	WithContext(context.Background(), nil)
 }
+
+func TestContextFields(t *testing.T) {
+	var got []interface{}
+	l := &testLogger{fn: func(level Level, kv ...interface{}) { got = kv }}
+	ctx := NewContext(context.Background(), "a", 1)
+	ctx = NewContext(ctx, "b", Valuer(func(context.Context) interface{} { return 2 }))
+	_ = WithContext(ctx, With(l, "p", 0)).Log(LevelInfo, "k", "v")
+	want := []interface{}{"p", 0, "a", 1, "b", 2, "k", "v"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v got %v", want, got)
+		}
+	}
+	if kv, ok := FromContext(context.Background()); ok || kv != nil {
+		t.Errorf("expected no fields got %v", kv)
+	}
+}
+
+type testLogger struct {
+	fn func(level Level, kv ...interface{})
+}
+
+func (l *testLogger) Log(level Level, kv ...interface{}) error {
+	l.fn(level, kv...)
+	return nil
+}
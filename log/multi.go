@@ -0,0 +1,65 @@
+package log
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+var _ Logger = (*MultiLogger)(nil)
+
+// MultiLogger is a logger that fans out logs to multiple loggers.
+// Wrap each logger with NewFilter to apply its own level filtering,
+// and use With on the MultiLogger to share fields and valuers between loggers.
+type MultiLogger struct {
+	sinks []*sink
+}
+
+type sink struct {
+	logger Logger
+	errors uint64
+}
+
+// NewMultiLogger new a logger that writes logs to all the loggers.
+// A failure or panic of one logger does not affect the others.
+func NewMultiLogger(loggers ...Logger) *MultiLogger {
+	sinks := make([]*sink, 0, len(loggers))
+	for _, l := range loggers {
+		sinks = append(sinks, &sink{logger: l})
+	}
+	return &MultiLogger{sinks: sinks}
+}
+
+// Log print log by level and keyvals to all the loggers,
+// it returns the first error that occurred.
+func (m *MultiLogger) Log(level Level, keyvals ...interface{}) (err error) {
+	for _, s := range m.sinks {
+		// each logger gets its own copy, since filters may change keyvals in place.
+		kvs := make([]interface{}, len(keyvals))
+		copy(kvs, keyvals)
+		if e := s.log(level, kvs); e != nil {
+			atomic.AddUint64(&s.errors, 1)
+			if err == nil {
+				err = e
+			}
+		}
+	}
+	return
+}
+
+// Errors returns the error counters of the loggers in order.
+func (m *MultiLogger) Errors() []uint64 {
+	counters := make([]uint64, 0, len(m.sinks))
+	for _, s := range m.sinks {
+		counters = append(counters, atomic.LoadUint64(&s.errors))
+	}
+	return counters
+}
+
+func (s *sink) log(level Level, keyvals []interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("log: logger panic: %v", r)
+		}
+	}()
+	return s.logger.Log(level, keyvals...)
+}
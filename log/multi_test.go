@@ -0,0 +1,48 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type errLogger struct{}
+
+func (errLogger) Log(Level, ...interface{}) error {
+	return errors.New("sink unavailable")
+}
+
+type panicLogger struct{}
+
+func (panicLogger) Log(Level, ...interface{}) error {
+	panic("boom")
+}
+
+func TestMultiLogger(t *testing.T) {
+	debug := bytes.NewBuffer(nil)
+	errs := bytes.NewBuffer(nil)
+	multi := NewMultiLogger(
+		NewFilter(NewStdLogger(debug), FilterLevel(LevelDebug)),
+		NewFilter(NewStdLogger(errs), FilterLevel(LevelError), FilterKey("password")),
+		errLogger{},
+		panicLogger{},
+	)
+	log := NewHelper(With(multi, "service", "kratos"))
+	log.Debugw("password", "123456")
+	log.Error("failed")
+
+	if got := debug.String(); !strings.Contains(got, "DEBUG service=kratos password=123456") || !strings.Contains(got, "ERROR service=kratos msg=failed") {
+		t.Errorf("unexpected debug sink output %q", got)
+	}
+	if got := errs.String(); got != "ERROR service=kratos msg=failed\n" {
+		t.Errorf("unexpected error sink output %q", got)
+	}
+	if err := multi.Log(LevelInfo, "k", "v"); err == nil {
+		t.Errorf("expected error from failed sink")
+	}
+	counters := multi.Errors()
+	if len(counters) != 4 || counters[0] != 0 || counters[1] != 0 || counters[2] != 3 || counters[3] != 3 {
+		t.Errorf("unexpected error counters %v", counters)
+	}
+}
@@ -38,6 +38,12 @@ func (l *stdLogger) Log(level Level, keyvals ...interface{}) error {
 	buf := l.pool.Get().(*bytes.Buffer)
 	buf.WriteString(level.String())
 	for i := 0; i < len(keyvals); i += 2 {
+		if group, ok := keyvals[i+1].(GroupValue); ok {
+			group.flatten(fmt.Sprint(keyvals[i]), func(key string, value interface{}) {
+				_, _ = fmt.Fprintf(buf, " %s=%v", key, value)
+			})
+			continue
+		}
 		_, _ = fmt.Fprintf(buf, " %s=%v", keyvals[i], keyvals[i+1])
 	}
 	_ = l.log.Output(4, buf.String()) //nolint:gomnd
@@ -0,0 +1,119 @@
+// Package authz authorizes the operations by the identity of the peer,
+// which is the SPIFFE ID or the SAN of the mutual TLS client certificate.
+package authz
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+const reason = "FORBIDDEN"
+
+var (
+	// ErrMissingPeer is returned when the peer identity is not present.
+	ErrMissingPeer = errors.Unauthorized("UNAUTHORIZED", "peer identity is missing")
+	// ErrForbidden is returned when the peer is not allowed to call the operation.
+	ErrForbidden = errors.Forbidden(reason, "peer is not allowed to call the operation")
+)
+
+// Option is authz option.
+type Option func(*options)
+
+type options struct {
+	rules        map[string][]string
+	defaultAllow bool
+}
+
+// Allow allows the identities to call the operations matched by the selector,
+// the selector is an operation or a prefix ending with '/*', the longest one wins,
+// and an identity ending with '*' matches the identities with the prefix.
+// selector:
+//   - '/*'
+//   - '/helloworld.v1.Greeter/*'
+//   - '/helloworld.v1.Greeter/SayHello'
+//
+// e.g. Allow("/helloworld.v1.Greeter/*", "spiffe://cluster.local/ns/default/*")
+func Allow(selector string, identities ...string) Option {
+	return func(o *options) {
+		o.rules[selector] = append(o.rules[selector], identities...)
+	}
+}
+
+// WithDefaultAllow allows the operations without any matched rule,
+// which are forbidden by default.
+func WithDefaultAllow() Option {
+	return func(o *options) {
+		o.defaultAllow = true
+	}
+}
+
+// Server is a server middleware which authorizes the operations by the peer identity,
+// the operations without any matched rule are forbidden unless WithDefaultAllow.
+func Server(opts ...Option) middleware.Middleware {
+	o := &options{rules: make(map[string][]string)}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromServerContext(ctx)
+			if !ok {
+				return nil, ErrForbidden
+			}
+			allowed, ok := o.match(tr.Operation())
+			if !ok {
+				if o.defaultAllow {
+					return handler(ctx, req)
+				}
+				return nil, ErrForbidden
+			}
+			p, ok := transport.PeerFromServerContext(ctx)
+			if !ok {
+				return nil, ErrMissingPeer
+			}
+			id := p.Identity()
+			if id == "" {
+				return nil, ErrMissingPeer
+			}
+			for _, a := range allowed {
+				if matchIdentity(a, id) {
+					return handler(ctx, req)
+				}
+			}
+			return nil, ErrForbidden
+		}
+	}
+}
+
+// match returns the identities of the exact operation, or of the longest matched prefix.
+func (o *options) match(operation string) ([]string, bool) {
+	if ids, ok := o.rules[operation]; ok {
+		return ids, true
+	}
+	var (
+		ids    []string
+		found  bool
+		longer int
+	)
+	for selector, v := range o.rules {
+		if !strings.HasSuffix(selector, "/*") {
+			continue
+		}
+		prefix := strings.TrimSuffix(selector, "*")
+		if strings.HasPrefix(operation, prefix) && (!found || len(prefix) > longer) {
+			ids, found, longer = v, true, len(prefix)
+		}
+	}
+	return ids, found
+}
+
+func matchIdentity(pattern, id string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(id, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == id
+}
@@ -0,0 +1,89 @@
+package authz
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/url"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+type Transport struct {
+	transport.Transporter
+	operation string
+	peer      *transport.Peer
+}
+
+func (tr *Transport) Operation() string     { return tr.operation }
+func (tr *Transport) Peer() *transport.Peer { return tr.peer }
+
+func newPeer(uri string) *transport.Peer {
+	u, _ := url.Parse(uri)
+	cert := &x509.Certificate{URIs: []*url.URL{u}}
+	return &transport.Peer{TLS: &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	}}
+}
+
+// newUnverifiedPeer returns the peer of a certificate which is not verified,
+// e.g. a self-signed one with a forged SPIFFE ID accepted by tls.RequireAnyClientCert.
+func newUnverifiedPeer(uri string) *transport.Peer {
+	p := newPeer(uri)
+	p.TLS.VerifiedChains = nil
+	return p
+}
+
+func TestServer(t *testing.T) {
+	m := Server(
+		Allow("/helloworld.v1.Greeter/*", "spiffe://kratos/ns/default/*"),
+		Allow("/helloworld.v1.Greeter/Admin", "spiffe://kratos/ns/admin/sa/ops"),
+		Allow("/helloworld.v1.Greeter/Admin/*", "spiffe://kratos/ns/nobody"),
+	)
+	next := func(context.Context, interface{}) (interface{}, error) { return "reply", nil }
+	tests := []struct {
+		name      string
+		operation string
+		peer      *transport.Peer
+		err       error
+	}{
+		{"prefix", "/helloworld.v1.Greeter/SayHello", newPeer("spiffe://kratos/ns/default/sa/client"), nil},
+		{"prefix forbidden", "/helloworld.v1.Greeter/SayHello", newPeer("spiffe://kratos/ns/other/sa/client"), ErrForbidden},
+		{"exact", "/helloworld.v1.Greeter/Admin", newPeer("spiffe://kratos/ns/admin/sa/ops"), nil},
+		{"exact forbidden", "/helloworld.v1.Greeter/Admin", newPeer("spiffe://kratos/ns/default/sa/client"), ErrForbidden},
+		{"longest prefix", "/helloworld.v1.Greeter/Admin/Reset", newPeer("spiffe://kratos/ns/default/sa/client"), ErrForbidden},
+		{"no rule", "/helloworld.v1.Other/SayHello", nil, ErrForbidden},
+		{"no peer", "/helloworld.v1.Greeter/SayHello", nil, ErrMissingPeer},
+		{"unverified", "/helloworld.v1.Greeter/SayHello", newUnverifiedPeer("spiffe://kratos/ns/default/sa/client"), ErrMissingPeer},
+		{"no identity", "/helloworld.v1.Greeter/SayHello", &transport.Peer{Addr: "127.0.0.1:1234"}, ErrMissingPeer},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := transport.NewServerContext(context.Background(), &Transport{operation: test.operation, peer: test.peer})
+			reply, err := m(next)(ctx, nil)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+			if err == nil && reply != "reply" {
+				t.Errorf("unexpected reply: %v", reply)
+			}
+		})
+	}
+}
+
+func TestDefaultAllow(t *testing.T) {
+	next := func(context.Context, interface{}) (interface{}, error) { return "reply", nil }
+	ctx := transport.NewServerContext(context.Background(), &Transport{operation: "/helloworld.v1.Other/SayHello"})
+	if _, err := Server(Allow("/helloworld.v1.Greeter/*", "*"))(next)(ctx, nil); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected the operation without rule forbidden, got %v", err)
+	}
+	if _, err := Server(Allow("/helloworld.v1.Greeter/*", "*"), WithDefaultAllow())(next)(ctx, nil); err != nil {
+		t.Errorf("expected the operation without rule allowed, got %v", err)
+	}
+	if _, err := Server(WithDefaultAllow())(next)(context.Background(), nil); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected the call without transport forbidden, got %v", err)
+	}
+}
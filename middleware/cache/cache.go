@@ -0,0 +1,500 @@
+// Package cache caches the replies of the client calls in memory, the HTTP
+// responses are cached as the Cache-Control and ETag headers direct, and the
+// concurrent identical calls are deduplicated.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+const (
+	resultHit         = "hit"
+	resultMiss        = "miss"
+	resultRevalidated = "revalidated"
+)
+
+// Option is cache option.
+type Option func(*options)
+
+// WithTTL with the time to live of the replies without the Cache-Control max-age,
+// default 0, which caches them only if they can be revalidated by ETag.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithMaxEntries with the maximum entries of the cache, the least recently used
+// entry is evicted when it is full, default 1024.
+func WithMaxEntries(n int) Option {
+	return func(o *options) {
+		o.maxEntries = n
+	}
+}
+
+// WithTimeout with the timeout of the call shared by the concurrent identical calls,
+// which is detached from the cancellation of the callers, default the deadline of
+// the caller which sends it.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithOperations with the operations of the gRPC and the other non-HTTP transports
+// whose replies are cached, since they are not known to be safe by the methods like
+// HTTP GET, an operation ending with '/*' matches the operations with the prefix.
+// e.g. WithOperations("/helloworld.v1.Greeter/GetConfig", "/config.v1.Config/*")
+func WithOperations(operations ...string) Option {
+	return func(o *options) {
+		o.operations = append(o.operations, operations...)
+	}
+}
+
+// WithKeyHeaders with the request headers or metadata added to the cache key, e.g. of
+// the tenant, so that the callers of the different values don't share the replies,
+// and the HTTP responses which Vary by the other headers are not cached.
+func WithKeyHeaders(keys ...string) Option {
+	return func(o *options) {
+		for _, k := range keys {
+			o.keyHeaders = append(o.keyHeaders, http.CanonicalHeaderKey(k))
+		}
+	}
+}
+
+// WithRequests with the counter of the cache lookups.
+func WithRequests(c metrics.Counter) Option {
+	return func(o *options) {
+		o.requests = c
+	}
+}
+
+type options struct {
+	ttl        time.Duration
+	timeout    time.Duration
+	maxEntries int
+	operations []string
+	keyHeaders []string
+	// counter: client_cache_requests_total{kind, operation, result}
+	requests metrics.Counter
+}
+
+type entry struct {
+	key     string
+	reply   interface{}
+	etag    string
+	expires time.Time
+}
+
+type cache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+func (c *cache) get(key string) *entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.ll.MoveToFront(e)
+		return e.Value.(*entry)
+	}
+	return nil
+}
+
+func (c *cache) set(e *entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[e.key]; ok {
+		el.Value = e
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[e.key] = c.ll.PushFront(e)
+	if c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry).key)
+	}
+}
+
+func (c *cache) delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.ll.Remove(el)
+		delete(c.items, key)
+	}
+}
+
+// Client is a client middleware which caches the replies keyed by the operation
+// and the hash of the request, the HTTP calls other than GET and HEAD, the calls
+// of the other transports not allowed by WithOperations, and the calls without
+// a request message, e.g. by http.Client.Do, are not cached.
+// The cache is shared by the callers, so the requests with the Authorization or
+// If-None-Match header and the private responses are not cached, see WithKeyHeaders
+// for the other headers which identify the callers.
+// The concurrent identical calls share the reply of the one sent to the server,
+// and the callers get the deep copies of the replies, the replies which can't be
+// copied, e.g. the structs with unexported fields, are not cached.
+func Client(opts ...Option) middleware.Middleware {
+	o := options{maxEntries: 1024}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	c := &cache{maxEntries: o.maxEntries, ll: list.New(), items: make(map[string]*list.Element)}
+	var group singleflight.Group
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromClientContext(ctx)
+			if !ok || req == nil || !o.cacheable(tr) {
+				return handler(ctx, req)
+			}
+			header := tr.RequestHeader()
+			reqDirectives := parseCacheControl(header.Get("Cache-Control"))
+			if _, ok := reqDirectives["no-store"]; ok || header.Get("Authorization") != "" || header.Get("If-None-Match") != "" {
+				return handler(ctx, req)
+			}
+			key, err := o.cacheKey(tr, req)
+			if err != nil {
+				return handler(ctx, req)
+			}
+			e := c.get(key)
+			if _, ok := reqDirectives["no-cache"]; !ok && e != nil && time.Now().Before(e.expires) {
+				o.report(tr, resultHit)
+				reply, _ := clone(e.reply)
+				return reply, nil
+			}
+			ch := group.DoChan(key, func() (interface{}, error) {
+				// the call is shared by the callers, so it is not canceled with the one sending it.
+				sctx, cancel := o.detach(ctx)
+				defer cancel()
+				if e != nil && e.etag != "" {
+					defer revalidate(tr, e.etag)()
+				}
+				reply, err := handler(sctx, req)
+				if e != nil && e.etag != "" && errors.Code(err) == http.StatusNotModified {
+					o.report(tr, resultRevalidated)
+					if ne := o.newEntry(key, e.reply, tr.ReplyHeader()); ne != nil {
+						if ne.etag == "" {
+							ne.etag = e.etag
+						}
+						c.set(ne)
+					}
+					return e.reply, nil
+				}
+				o.report(tr, resultMiss)
+				if err != nil || reply == nil {
+					return reply, err
+				}
+				if cached, ok := clone(reply); ok {
+					if ne := o.newEntry(key, cached, tr.ReplyHeader()); ne != nil {
+						c.set(ne)
+						return reply, nil
+					}
+				}
+				c.delete(key)
+				return reply, nil
+			})
+			var res singleflight.Result
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case res = <-ch:
+			}
+			v, err, shared := res.Val, res.Err, res.Shared
+			if err != nil || v == nil {
+				return v, err
+			}
+			// each caller gets its own copy of the shared reply, the reply which
+			// can't be copied is not shared, and the callers send their own calls.
+			if reply, ok := clone(v); ok {
+				return reply, nil
+			}
+			if shared {
+				return handler(ctx, req)
+			}
+			return v, nil
+		}
+	}
+}
+
+// detach returns the context of the shared call, which keeps the values of ctx
+// without its cancellation, and is canceled by the timeout or the deadline of ctx.
+func (o *options) detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.timeout > 0 {
+		return context.WithTimeout(detachedContext{ctx}, o.timeout)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detachedContext{ctx}, deadline)
+	}
+	return context.WithCancel(detachedContext{ctx})
+}
+
+// detachedContext is the context which is never canceled, but carries the values
+// of the parent, e.g. the transport and metadata.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+func (o *options) report(tr transport.Transporter, result string) {
+	if o.requests != nil {
+		o.requests.With(tr.Kind().String(), tr.Operation(), result).Inc()
+	}
+}
+
+// newEntry returns the entry of the reply, nil if the reply must not be cached.
+func (o *options) newEntry(key string, reply interface{}, header transport.Header) *entry {
+	e := &entry{key: key, reply: reply}
+	if header != nil {
+		e.etag = header.Get("ETag")
+	}
+	ttl := o.ttl
+	if header != nil {
+		directives := parseCacheControl(header.Get("Cache-Control"))
+		if _, ok := directives["no-store"]; ok {
+			return nil
+		}
+		if _, ok := directives["private"]; ok {
+			return nil
+		}
+		if !o.varyKeyHeaders(header.Get("Vary")) {
+			return nil
+		}
+		if v, ok := directives["max-age"]; ok {
+			if n, err := strconv.Atoi(v); err == nil {
+				ttl = time.Duration(n) * time.Second
+			}
+		}
+		if _, ok := directives["no-cache"]; ok {
+			ttl = 0
+		}
+	}
+	if ttl <= 0 && e.etag == "" {
+		return nil
+	}
+	e.expires = time.Now().Add(ttl)
+	return e
+}
+
+// varyKeyHeaders reports whether the headers of the Vary response header are
+// all in the cache key, so that the response can be shared by the callers.
+func (o *options) varyKeyHeaders(vary string) bool {
+	for _, v := range strings.Split(vary, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if v == "*" {
+			return false
+		}
+		found := false
+		for _, k := range o.keyHeaders {
+			if k == http.CanonicalHeaderKey(v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// revalidate sets the If-None-Match header of the ETag on the request of the shared
+// call, and returns the func which removes it after the call.
+func revalidate(tr transport.Transporter, etag string) func() {
+	tr.RequestHeader().Set("If-None-Match", etag)
+	if hr, ok := tr.(interface{ Request() *http.Request }); ok {
+		if req := hr.Request(); req != nil {
+			return func() { req.Header.Del("If-None-Match") }
+		}
+	}
+	return func() { tr.RequestHeader().Set("If-None-Match", "") }
+}
+
+// cacheable reports whether the call is cacheable, which is the HTTP GET or HEAD
+// request, or the operation allowed by WithOperations.
+func (o *options) cacheable(tr transport.Transporter) bool {
+	if hr, ok := tr.(interface{ Request() *http.Request }); ok {
+		if req := hr.Request(); req != nil {
+			return req.Method == http.MethodGet || req.Method == http.MethodHead
+		}
+	}
+	operation := tr.Operation()
+	for _, v := range o.operations {
+		if v == operation || (strings.HasSuffix(v, "/*") && strings.HasPrefix(operation, strings.TrimSuffix(v, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheKey returns the key of the call, the URL of the HTTP request is included
+// since the path and query parameters may be set by the call options, as well as
+// the headers of WithKeyHeaders.
+func (o *options) cacheKey(tr transport.Transporter, req interface{}) (string, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if m, ok := req.(proto.Message); ok {
+		data, err = proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	} else {
+		data, err = json.Marshal(req)
+	}
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(tr.Endpoint() + "\n" + tr.Operation() + "\n"))
+	if hr, ok := tr.(interface{ Request() *http.Request }); ok {
+		if r := hr.Request(); r != nil {
+			h.Write([]byte(r.Method + " " + r.URL.String() + "\n"))
+		}
+	}
+	for _, k := range o.keyHeaders {
+		h.Write([]byte(k + ": " + tr.RequestHeader().Get(k) + "\n"))
+	}
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// clone returns a deep copy of the reply, so that the cached reply is not changed
+// by the callers, false if the reply can't be copied, e.g. a struct with unexported
+// fields, which is not cached.
+func clone(reply interface{}) (interface{}, bool) {
+	if m, ok := reply.(proto.Message); ok {
+		return proto.Clone(m), true
+	}
+	v, ok := deepCopy(reflect.ValueOf(reply))
+	if !ok {
+		return nil, false
+	}
+	return v.Interface(), true
+}
+
+func deepCopy(v reflect.Value) (reflect.Value, bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return reflect.Zero(v.Type()), true
+		}
+		if m, ok := v.Interface().(proto.Message); ok {
+			return reflect.ValueOf(proto.Clone(m)), true
+		}
+		e, ok := deepCopy(v.Elem())
+		if !ok {
+			return reflect.Value{}, false
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(e)
+		return c, true
+	case reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(v.Type()), true
+		}
+		e, ok := deepCopy(v.Elem())
+		if !ok {
+			return reflect.Value{}, false
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(e)
+		return c, true
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(v.Type()), true
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			e, ok := deepCopy(iter.Value())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			c.SetMapIndex(iter.Key(), e)
+		}
+		return c, true
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type()), true
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			e, ok := deepCopy(v.Index(i))
+			if !ok {
+				return reflect.Value{}, false
+			}
+			c.Index(i).Set(e)
+		}
+		return c, true
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			e, ok := deepCopy(v.Index(i))
+			if !ok {
+				return reflect.Value{}, false
+			}
+			c.Index(i).Set(e)
+		}
+		return c, true
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			if !c.Field(i).CanSet() {
+				return reflect.Value{}, false
+			}
+			e, ok := deepCopy(v.Field(i))
+			if !ok {
+				return reflect.Value{}, false
+			}
+			c.Field(i).Set(e)
+		}
+		return c, true
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer, reflect.Invalid:
+		return reflect.Value{}, false
+	default:
+		return v, true
+	}
+}
+
+func parseCacheControl(v string) map[string]string {
+	directives := make(map[string]string)
+	for _, d := range strings.Split(v, ",") {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		name, value := d, ""
+		if i := strings.IndexByte(d, '='); i >= 0 {
+			name, value = d[:i], strings.Trim(d[i+1:], `"`)
+		}
+		directives[strings.ToLower(name)] = value
+	}
+	return directives
+}
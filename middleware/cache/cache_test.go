@@ -0,0 +1,440 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/transport"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+)
+
+type mockCounter struct {
+	mu     *sync.Mutex
+	lvs    []string
+	values map[string]float64
+}
+
+func newMockCounter() *mockCounter {
+	return &mockCounter{mu: new(sync.Mutex), values: make(map[string]float64)}
+}
+
+func (c *mockCounter) With(lvs ...string) metrics.Counter {
+	return &mockCounter{mu: c.mu, lvs: lvs, values: c.values}
+}
+
+func (c *mockCounter) Inc() { c.Add(1) }
+func (c *mockCounter) Add(delta float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[c.lvs[len(c.lvs)-1]] += delta
+}
+
+func (c *mockCounter) get(result string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[result]
+}
+
+type headerCarrier http.Header
+
+func (hc headerCarrier) Get(key string) string        { return http.Header(hc).Get(key) }
+func (hc headerCarrier) Set(key string, value string) { http.Header(hc).Set(key, value) }
+func (hc headerCarrier) Keys() []string               { return nil }
+
+type Transport struct {
+	transport.Transporter
+	operation   string
+	reqHeader   headerCarrier
+	replyHeader headerCarrier
+}
+
+func (tr *Transport) Kind() transport.Kind            { return transport.KindGRPC }
+func (tr *Transport) Endpoint() string                { return "discovery:///greeter" }
+func (tr *Transport) Operation() string               { return tr.operation }
+func (tr *Transport) RequestHeader() transport.Header { return tr.reqHeader }
+func (tr *Transport) ReplyHeader() transport.Header   { return tr.replyHeader }
+
+func newContext(operation string) context.Context {
+	return transport.NewClientContext(context.Background(), &Transport{
+		operation:   operation,
+		reqHeader:   headerCarrier{},
+		replyHeader: headerCarrier{},
+	})
+}
+
+func newRequest(name string) *wrapperspb.StringValue { return wrapperspb.String(name) }
+func newReply(reply string) *wrapperspb.StringValue  { return wrapperspb.String(reply) }
+func replyValue(reply interface{}) string            { return reply.(*wrapperspb.StringValue).Value }
+
+func TestHTTPClient(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		switch r.URL.Path {
+		case "/config":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/metadata":
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/volatile":
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	}))
+	defer srv.Close()
+
+	requests := newMockCounter()
+	client, err := khttp.NewClient(context.Background(),
+		khttp.WithEndpoint(strings.TrimPrefix(srv.URL, "http://")),
+		khttp.WithMiddleware(Client(WithRequests(requests))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func(method, path string) string {
+		reply := make(map[string]string)
+		if err := client.Invoke(context.Background(), method, path, map[string]string{}, &reply); err != nil {
+			t.Fatal(err)
+		}
+		return reply["path"]
+	}
+	tests := []struct {
+		method string
+		path   string
+		calls  int32
+	}{
+		{http.MethodGet, "/config", 1},
+		{http.MethodGet, "/metadata", 2},
+		{http.MethodGet, "/volatile", 2},
+		{http.MethodPost, "/config", 2},
+	}
+	for _, test := range tests {
+		atomic.StoreInt32(&calls, 0)
+		for i := 0; i < 2; i++ {
+			if got := call(test.method, test.path); got != test.path {
+				t.Errorf("%s %s: unexpected reply %q", test.method, test.path, got)
+			}
+		}
+		if got := atomic.LoadInt32(&calls); got != test.calls {
+			t.Errorf("%s %s: expected %d calls, got %d", test.method, test.path, test.calls, got)
+		}
+	}
+	if requests.get(resultHit) != 1 || requests.get(resultRevalidated) != 1 || requests.get(resultMiss) != 4 {
+		t.Errorf("unexpected metrics: %v", requests.values)
+	}
+}
+
+func TestSingleflight(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	m := Client(WithTTL(time.Minute), WithOperations("/helloworld.v1.Greeter/*"))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return newReply("hello " + replyValue(req)), nil
+	})
+	var wg sync.WaitGroup
+	replies := make([]interface{}, 10)
+	for i := range replies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			replies[i], _ = m(newContext("/helloworld.v1.Greeter/SayHello"), newRequest("kratos"))
+		}(i)
+	}
+	// wait for the calls to be blocked on the first one.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+	for _, reply := range replies {
+		if replyValue(reply) != "hello kratos" {
+			t.Errorf("unexpected reply: %v", reply)
+		}
+	}
+	// a different request is not shared.
+	if reply, _ := m(newContext("/helloworld.v1.Greeter/SayHello"), newRequest("go")); replyValue(reply) != "hello go" {
+		t.Errorf("unexpected reply: %v", reply)
+	}
+}
+
+func TestSingleflightCanceled(t *testing.T) {
+	release := make(chan struct{})
+	m := Client(WithTTL(time.Minute), WithTimeout(time.Second), WithOperations("/helloworld.v1.Greeter/SayHello"))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		if _, ok := transport.FromClientContext(ctx); !ok {
+			t.Error("expected the transport of the caller")
+		}
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return newReply("hello " + replyValue(req)), nil
+	})
+	ctx, cancel := context.WithCancel(newContext("/helloworld.v1.Greeter/SayHello"))
+	errc := make(chan error, 1)
+	go func() {
+		_, err := m(ctx, newRequest("kratos"))
+		errc <- err
+	}()
+	// wait for the call to be sent by the first caller.
+	time.Sleep(50 * time.Millisecond)
+	replyc := make(chan interface{}, 1)
+	go func() {
+		reply, _ := m(newContext("/helloworld.v1.Greeter/SayHello"), newRequest("kratos"))
+		replyc <- reply
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("expected the first caller canceled, got %v", err)
+	}
+	close(release)
+	if reply := <-replyc; reply == nil || replyValue(reply) != "hello kratos" {
+		t.Errorf("expected the shared call not to be canceled, got %v", reply)
+	}
+}
+
+func TestMaxEntries(t *testing.T) {
+	var calls int32
+	m := Client(WithTTL(time.Minute), WithMaxEntries(1), WithOperations("/test"))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return newReply(replyValue(req)), nil
+	})
+	for _, name := range []string{"a", "a", "b", "a"} {
+		if reply, _ := m(newContext("/test"), newRequest(name)); replyValue(reply) != name {
+			t.Errorf("unexpected reply: %v", reply)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+	// the request with no-cache revalidates the cached reply.
+	ctx := newContext("/test")
+	tr, _ := transport.FromClientContext(ctx)
+	tr.RequestHeader().Set("Cache-Control", "no-cache")
+	_, _ = m(ctx, newRequest("a"))
+	if calls != 4 {
+		t.Errorf("expected 4 calls, got %d", calls)
+	}
+}
+
+func TestClone(t *testing.T) {
+	type item struct {
+		Names []string
+		Attrs map[string]*int
+	}
+	n := 1
+	src := &item{Names: []string{"a"}, Attrs: map[string]*int{"n": &n}}
+	v, ok := clone(src)
+	if !ok {
+		t.Fatal("expected the reply to be copied")
+	}
+	dst := v.(*item)
+	dst.Names[0] = "b"
+	*dst.Attrs["n"] = 2
+	if src.Names[0] != "a" || n != 1 {
+		t.Errorf("expected the deep copy, got %v %v", src.Names, n)
+	}
+	if _, ok = clone(&struct{ n []int }{}); ok {
+		t.Error("expected the struct with unexported fields not to be copied")
+	}
+	if _, ok = clone(func() {}); ok {
+		t.Error("expected the func not to be copied")
+	}
+}
+
+func TestCachedReply(t *testing.T) {
+	var calls int32
+	m := Client(WithTTL(time.Minute), WithOperations("/test"))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return map[string]string{"name": replyValue(req)}, nil
+	})
+	for i := 0; i < 2; i++ {
+		reply, err := m(newContext("/test"), newRequest("kratos"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := reply.(map[string]string)
+		if v["name"] != "kratos" {
+			t.Errorf("expected the cached reply not to be changed by the caller, got %v", v)
+		}
+		v["name"] = "changed"
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestOperations(t *testing.T) {
+	o := &options{operations: []string{"/helloworld.v1.Greeter/SayHello", "/config.v1.Config/*"}}
+	tests := map[string]bool{
+		"/helloworld.v1.Greeter/SayHello":  true,
+		"/helloworld.v1.Greeter/SayHello2": false,
+		"/config.v1.Config/GetConfig":      true,
+		"/config.v1.Configs/GetConfig":     false,
+	}
+	for operation, want := range tests {
+		if got := o.cacheable(&Transport{operation: operation}); got != want {
+			t.Errorf("%s: expected cacheable %v, got %v", operation, want, got)
+		}
+	}
+	// the gRPC calls are not cached without the operations.
+	var calls int32
+	m := Client(WithTTL(time.Minute))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return newReply(replyValue(req)), nil
+	})
+	for i := 0; i < 2; i++ {
+		_, _ = m(newContext("/helloworld.v1.Greeter/SayHello"), newRequest("kratos"))
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	var calls int32
+	m := Client(WithTTL(time.Minute), WithOperations("/test"))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		tr, _ := transport.FromClientContext(ctx)
+		return newReply(tr.RequestHeader().Get("Authorization")), nil
+	})
+	for _, token := range []string{"Bearer alice", "Bearer bob", "Bearer alice"} {
+		ctx := newContext("/test")
+		tr, _ := transport.FromClientContext(ctx)
+		tr.RequestHeader().Set("Authorization", token)
+		if reply, _ := m(ctx, newRequest("kratos")); replyValue(reply) != token {
+			t.Errorf("expected the reply of %s, got %v", token, reply)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestKeyHeaders(t *testing.T) {
+	var calls int32
+	m := Client(WithTTL(time.Minute), WithOperations("/test"), WithKeyHeaders("x-tenant"))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		tr, _ := transport.FromClientContext(ctx)
+		return newReply(tr.RequestHeader().Get("X-Tenant")), nil
+	})
+	for _, tenant := range []string{"a", "b", "a", "b"} {
+		ctx := newContext("/test")
+		tr, _ := transport.FromClientContext(ctx)
+		tr.RequestHeader().Set("X-Tenant", tenant)
+		if reply, _ := m(ctx, newRequest("kratos")); replyValue(reply) != tenant {
+			t.Errorf("expected the reply of %s, got %v", tenant, reply)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+
+	o := &options{ttl: time.Minute, keyHeaders: []string{"X-Tenant"}}
+	for vary, cached := range map[string]bool{
+		"":                          true,
+		"x-tenant":                  true,
+		"X-Tenant, Accept-Language": false,
+		"*":                         false,
+	} {
+		header := headerCarrier{}
+		header.Set("Vary", vary)
+		if e := o.newEntry("key", nil, header); (e != nil) != cached {
+			t.Errorf("Vary %q: expected cached %v, got %v", vary, cached, e)
+		}
+	}
+}
+
+type httpTransport struct {
+	*Transport
+	req *http.Request
+}
+
+func (tr *httpTransport) Request() *http.Request { return tr.req }
+
+func TestRevalidate(t *testing.T) {
+	var inm []string
+	m := Client(WithOperations("/test"))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		tr, _ := transport.FromClientContext(ctx)
+		v := tr.RequestHeader().Get("If-None-Match")
+		inm = append(inm, v)
+		tr.ReplyHeader().Set("ETag", `"v1"`)
+		if v == `"v1"` {
+			return nil, errors.New(http.StatusNotModified, "", "")
+		}
+		return newReply("kratos"), nil
+	})
+	newHTTPContext := func(inm string) (context.Context, *http.Request) {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		if inm != "" {
+			req.Header.Set("If-None-Match", inm)
+		}
+		tr := &httpTransport{
+			Transport: &Transport{operation: "/test", reqHeader: headerCarrier(req.Header), replyHeader: headerCarrier{}},
+			req:       req,
+		}
+		return transport.NewClientContext(context.Background(), tr), req
+	}
+	for _, v := range []string{"", "", `"v0"`} {
+		ctx, req := newHTTPContext(v)
+		reply, err := m(ctx, newRequest("kratos"))
+		if v == "" && (err != nil || replyValue(reply) != "kratos") {
+			t.Errorf("unexpected reply: %v %v", reply, err)
+		}
+		if got := req.Header.Get("If-None-Match"); got != v {
+			t.Errorf("expected the If-None-Match of the caller %q, got %q", v, got)
+		}
+	}
+	if want := []string{"", `"v1"`, `"v0"`}; !reflect.DeepEqual(inm, want) {
+		t.Errorf("expected the If-None-Match of the calls %q, got %q", want, inm)
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	o := &options{ttl: time.Minute}
+	tests := []struct {
+		header  string
+		etag    string
+		cached  bool
+		expires time.Duration
+	}{
+		{"", "", true, time.Minute},
+		{"max-age=10, public", "", true, 10 * time.Second},
+		{"no-store", `"v1"`, false, 0},
+		{"no-cache", "", false, 0},
+		{"no-cache", `"v1"`, true, 0},
+		{"private, max-age=60", "", false, 0},
+	}
+	for _, test := range tests {
+		header := headerCarrier{}
+		header.Set("Cache-Control", test.header)
+		header.Set("ETag", test.etag)
+		e := o.newEntry("key", nil, header)
+		if (e != nil) != test.cached {
+			t.Errorf("%q: expected cached %v, got %v", test.header, test.cached, e)
+			continue
+		}
+		if e != nil && time.Until(e.expires).Round(time.Second) != test.expires {
+			t.Errorf("%q: unexpected expires %v", test.header, time.Until(e.expires))
+		}
+	}
+}
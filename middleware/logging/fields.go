@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/tracing"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// FieldOption is request-scoped fields option.
+type FieldOption func(*fieldOptions)
+
+type fieldOptions struct {
+	identity func(ctx context.Context) string
+	fields   []interface{}
+}
+
+// WithIdentity with a func that returns the caller identity of the request,
+// such as the subject of the authenticated token.
+func WithIdentity(f func(ctx context.Context) string) FieldOption {
+	return func(o *fieldOptions) {
+		o.identity = f
+	}
+}
+
+// WithFields with custom request-scoped fields, values can be log.Valuer.
+func WithFields(kv ...interface{}) FieldOption {
+	return func(o *fieldOptions) {
+		o.fields = append(o.fields, kv...)
+	}
+}
+
+// Fields returns a middleware that injects request-scoped log fields
+// (operation, trace_id, span_id, identity and custom fields) into the context,
+// so that log.Context(ctx) and log.WithContext(ctx, logger) include them automatically.
+func Fields(opts ...FieldOption) middleware.Middleware {
+	o := &fieldOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			kvs := make([]interface{}, 0, 8+len(o.fields))
+			if info, ok := transport.FromServerContext(ctx); ok {
+				kvs = append(kvs, "operation", info.Operation())
+			} else if info, ok := transport.FromClientContext(ctx); ok {
+				kvs = append(kvs, "operation", info.Operation())
+			}
+			kvs = append(kvs, "trace_id", tracing.TraceID(), "span_id", tracing.SpanID())
+			if o.identity != nil {
+				kvs = append(kvs, "identity", o.identity(ctx))
+			}
+			kvs = append(kvs, o.fields...)
+			return handler(log.NewContext(ctx, kvs...), req)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+func TestFields(t *testing.T) {
+	bf := bytes.NewBuffer(nil)
+	logger := log.NewStdLogger(bf)
+
+	ctx := transport.NewServerContext(context.Background(), &Transport{kind: transport.KindHTTP, endpoint: "endpoint", operation: "/package.service/method"})
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		log.NewHelper(logger).WithContext(ctx).Info("hello")
+		return "reply", nil
+	}
+	m := Fields(
+		WithIdentity(func(context.Context) string { return "alice" }),
+		WithFields("tenant", "kratos"),
+	)
+	if _, err := m(next)(ctx, "req"); err != nil {
+		t.Fatal(err)
+	}
+	out := bf.String()
+	for _, want := range []string{
+		"operation=/package.service/method",
+		"trace_id=",
+		"span_id=",
+		"identity=alice",
+		"tenant=kratos",
+		"msg=hello",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in %q", want, out)
+		}
+	}
+}
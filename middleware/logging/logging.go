@@ -2,7 +2,6 @@ package logging
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/go-kratos/kratos/v2/errors"
@@ -11,8 +10,29 @@ import (
 	"github.com/go-kratos/kratos/v2/transport"
 )
 
+// Option is logging option.
+type Option func(*options)
+
+type options struct {
+	redactKeys []string
+}
+
+// WithRedactKeys with the field name patterns whose values are redacted in args and errors.
+// Patterns are matched case-insensitively using path.Match syntax, e.g. "password", "*token*".
+// Fields marked with the (kratos.sensitive) option are always redacted.
+func WithRedactKeys(patterns ...string) Option {
+	return func(o *options) {
+		o.redactKeys = append(o.redactKeys, patterns...)
+	}
+}
+
 // Server is an server logging middleware.
-func Server(logger log.Logger) middleware.Middleware {
+func Server(logger log.Logger, opts ...Option) middleware.Middleware {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	r := newRedactor(o.redactKeys)
 	return func(handler middleware.Handler) middleware.Handler {
 		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
 			var (
@@ -31,12 +51,12 @@ func Server(logger log.Logger) middleware.Middleware {
 				code = se.Code
 				reason = se.Reason
 			}
-			level, stack := extractError(err)
+			level, stack := r.extractError(err)
 			_ = log.WithContext(ctx, logger).Log(level,
 				"kind", "server",
 				"component", kind,
 				"operation", operation,
-				"args", extractArgs(req),
+				"args", r.args(req),
 				"code", code,
 				"reason", reason,
 				"stack", stack,
@@ -48,7 +68,12 @@ func Server(logger log.Logger) middleware.Middleware {
 }
 
 // Client is an client logging middleware.
-func Client(logger log.Logger) middleware.Middleware {
+func Client(logger log.Logger, opts ...Option) middleware.Middleware {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	r := newRedactor(o.redactKeys)
 	return func(handler middleware.Handler) middleware.Handler {
 		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
 			var (
@@ -67,12 +92,12 @@ func Client(logger log.Logger) middleware.Middleware {
 				code = se.Code
 				reason = se.Reason
 			}
-			level, stack := extractError(err)
+			level, stack := r.extractError(err)
 			_ = log.WithContext(ctx, logger).Log(level,
 				"kind", "client",
 				"component", kind,
 				"operation", operation,
-				"args", extractArgs(req),
+				"args", r.args(req),
 				"code", code,
 				"reason", reason,
 				"stack", stack,
@@ -85,16 +110,20 @@ func Client(logger log.Logger) middleware.Middleware {
 
 // extractArgs returns the string of the req
 func extractArgs(req interface{}) string {
-	if stringer, ok := req.(fmt.Stringer); ok {
-		return stringer.String()
-	}
-	return fmt.Sprintf("%+v", req)
+	return defaultRedactor.args(req)
 }
 
 // extractError returns the string of the error
 func extractError(err error) (log.Level, string) {
+	return defaultRedactor.extractError(err)
+}
+
+var defaultRedactor = newRedactor(nil)
+
+// extractError returns the level and the redacted string of the error
+func (r *redactor) extractError(err error) (log.Level, string) {
 	if err != nil {
-		return log.LevelError, fmt.Sprintf("%+v", err)
+		return log.LevelError, r.errorString(err)
 	}
 	return log.LevelInfo, ""
 }
@@ -46,7 +46,7 @@ func TestHTTP(t *testing.T) {
 
 	tests := []struct {
 		name string
-		kind func(logger log.Logger) middleware.Middleware
+		kind func(logger log.Logger, opts ...Option) middleware.Middleware
 		err  error
 		ctx  context.Context
 	}{
@@ -0,0 +1,129 @@
+package logging
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+//go:generate protoc -I ../../third_party --go_out=../.. --go_opt=module=github.com/go-kratos/kratos/v2 kratos/sensitive.proto
+
+const redactedStr = "***"
+
+// Redacter defines how to log an object.
+type Redacter interface {
+	Redact() string
+}
+
+type redactor struct {
+	keys []string
+}
+
+func newRedactor(keys []string) *redactor {
+	r := &redactor{keys: make([]string, 0, len(keys))}
+	for _, k := range keys {
+		r.keys = append(r.keys, strings.ToLower(k))
+	}
+	return r
+}
+
+// matchKey reports whether the key matches any of the redact patterns.
+func (r *redactor) matchKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range r.keys {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sensitive reports whether the field is marked with (kratos.sensitive) or matches the redact patterns.
+func (r *redactor) sensitive(fd protoreflect.FieldDescriptor) bool {
+	if opts := fd.Options(); opts != nil && proto.HasExtension(opts, E_Sensitive) {
+		if v, ok := proto.GetExtension(opts, E_Sensitive).(bool); ok && v {
+			return true
+		}
+	}
+	return r.matchKey(string(fd.Name())) || r.matchKey(fd.JSONName())
+}
+
+// args returns the string of the req with sensitive fields redacted.
+func (r *redactor) args(req interface{}) string {
+	if redacter, ok := req.(Redacter); ok {
+		return redacter.Redact()
+	}
+	if msg, ok := req.(proto.Message); ok && msg.ProtoReflect().IsValid() {
+		msg = proto.Clone(msg)
+		r.redactMessage(msg.ProtoReflect())
+		if stringer, ok := msg.(fmt.Stringer); ok {
+			return stringer.String()
+		}
+		return prototext.MarshalOptions{}.Format(msg)
+	}
+	if stringer, ok := req.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return fmt.Sprintf("%+v", req)
+}
+
+func (r *redactor) redactMessage(m protoreflect.Message) {
+	var masked []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if r.sensitive(fd) {
+			masked = append(masked, fd)
+			return true
+		}
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				r.redactMessage(list.Get(i).Message())
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				r.redactMessage(mv.Message())
+				return true
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			r.redactMessage(v.Message())
+		}
+		return true
+	})
+	for _, fd := range masked {
+		switch {
+		case fd.IsList() || fd.IsMap():
+			m.Clear(fd)
+		case fd.Kind() == protoreflect.StringKind:
+			m.Set(fd, protoreflect.ValueOfString(redactedStr))
+		case fd.Kind() == protoreflect.BytesKind:
+			m.Set(fd, protoreflect.ValueOfBytes([]byte(redactedStr)))
+		default:
+			m.Clear(fd)
+		}
+	}
+}
+
+// errorString returns the string of the error with sensitive metadata redacted.
+func (r *redactor) errorString(err error) string {
+	if redacter, ok := err.(Redacter); ok {
+		return redacter.Redact()
+	}
+	if se, ok := err.(*errors.Error); ok && len(r.keys) > 0 {
+		md := make(map[string]string, len(se.Metadata))
+		for k, v := range se.Metadata {
+			if r.matchKey(k) {
+				v = redactedStr
+			}
+			md[k] = v
+		}
+		err = se.WithMetadata(md)
+	}
+	return fmt.Sprintf("%+v", err)
+}
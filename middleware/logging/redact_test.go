@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+type redacted struct{}
+
+func (redacted) Redact() string {
+	return "redacted"
+}
+
+func (redacted) Error() string {
+	return "secret"
+}
+
+func newLoginRequest(t *testing.T) *dynamicpb.Message {
+	sensitive := &descriptorpb.FieldOptions{}
+	proto.SetExtension(sensitive, E_Sensitive, true)
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("logging/test.proto"),
+		Package: proto.String("logging.test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("LoginRequest"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("username"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), JsonName: proto.String("username")},
+				{Name: proto.String("password"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), JsonName: proto.String("password"), Options: sensitive},
+				{Name: proto.String("access_token"), Number: proto.Int32(3), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), JsonName: proto.String("accessToken")},
+			},
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := fd.Messages().ByName("LoginRequest")
+	m := dynamicpb.NewMessage(md)
+	m.Set(md.Fields().ByName("username"), protoreflect.ValueOfString("kratos"))
+	m.Set(md.Fields().ByName("password"), protoreflect.ValueOfString("p@ssw0rd"))
+	m.Set(md.Fields().ByName("access_token"), protoreflect.ValueOfString("t0ken"))
+	return m
+}
+
+func TestRedactArgs(t *testing.T) {
+	m := newLoginRequest(t)
+	out := newRedactor([]string{"*TOKEN*"}).args(m)
+	if strings.Contains(out, "p@ssw0rd") || strings.Contains(out, "t0ken") {
+		t.Errorf("expected sensitive fields redacted, got %s", out)
+	}
+	if !strings.Contains(out, "kratos") || !strings.Contains(out, redactedStr) {
+		t.Errorf("expected username kept and fields masked, got %s", out)
+	}
+	// the original request must not be changed
+	if v := m.Get(m.Descriptor().Fields().ByName("password")).String(); v != "p@ssw0rd" {
+		t.Errorf("expected original request untouched, got %s", v)
+	}
+	if out := newRedactor(nil).args(m); !strings.Contains(out, "t0ken") {
+		t.Errorf("expected token kept without patterns, got %s", out)
+	}
+	if out := extractArgs(redacted{}); out != "redacted" {
+		t.Errorf("expected redacted, got %s", out)
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	err := errors.New(400, "BAD_REQUEST", "bad request").WithMetadata(map[string]string{"token": "t0ken", "user": "kratos"})
+	out := newRedactor([]string{"token"}).errorString(err)
+	if strings.Contains(out, "t0ken") || !strings.Contains(out, "kratos") {
+		t.Errorf("expected token redacted, got %s", out)
+	}
+	if err.Metadata["token"] != "t0ken" {
+		t.Errorf("expected original error untouched")
+	}
+	if _, out := extractError(redacted{}); out != "redacted" {
+		t.Errorf("expected redacted, got %s", out)
+	}
+}
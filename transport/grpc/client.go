@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	ireply "github.com/go-kratos/kratos/v2/internal/reply"
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/go-kratos/kratos/v2/registry"
@@ -185,7 +186,12 @@ func unaryClientInterceptor(ms []middleware.Middleware, timeout time.Duration, f
 		}
 		var p selector.Peer
 		ctx = selector.NewPeerContext(ctx, &p)
-		_, err := h(ctx, req)
-		return err
+		res, err := h(ctx, req)
+		if err != nil {
+			return err
+		}
+		// the middleware may answer without calling the server, e.g. from a cache.
+		ireply.Copy(reply, res)
+		return nil
 	}
 }
@@ -21,6 +21,7 @@ func (s *Server) unaryServerInterceptor() grpc.UnaryServerInterceptor {
 			operation:   info.FullMethod,
 			reqHeader:   headerCarrier(md),
 			replyHeader: headerCarrier(replyHeader),
+			peer:        peerFromContext(ctx),
 		}
 		if s.endpoint != nil {
 			tr.endpoint = s.endpoint.String()
@@ -68,12 +69,16 @@ func (s *Server) streamServerInterceptor() grpc.StreamServerInterceptor {
 		defer cancel()
 		md, _ := grpcmd.FromIncomingContext(ctx)
 		replyHeader := grpcmd.MD{}
-		ctx = transport.NewServerContext(ctx, &Transport{
-			endpoint:    s.endpoint.String(),
+		tr := &Transport{
 			operation:   info.FullMethod,
 			reqHeader:   headerCarrier(md),
 			replyHeader: headerCarrier(replyHeader),
-		})
+			peer:        peerFromContext(ctx),
+		}
+		if s.endpoint != nil {
+			tr.endpoint = s.endpoint.String()
+		}
+		ctx = transport.NewServerContext(ctx, tr)
 
 		ws := NewWrappedStream(ctx, ss)
 
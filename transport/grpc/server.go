@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"net"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/go-kratos/kratos/v2/internal/endpoint"
@@ -119,6 +120,13 @@ type Server struct {
 	grpcOpts   []grpc.ServerOption
 	health     *health.Server
 	metadata   *apimd.Server
+	servicesMu sync.Mutex
+	services   []registeredService
+}
+
+type registeredService struct {
+	desc *grpc.ServiceDesc
+	impl interface{}
 }
 
 // NewServer creates a gRPC server by options.
@@ -174,6 +182,27 @@ func (s *Server) Use(selector string, m ...middleware.Middleware) {
 	s.middleware.Add(selector, m...)
 }
 
+// RegisterService registers a service and its implementation to the gRPC server,
+// the service is recorded so that it can be registered to other registrars.
+func (s *Server) RegisterService(desc *grpc.ServiceDesc, impl interface{}) {
+	s.Server.RegisterService(desc, impl)
+	s.servicesMu.Lock()
+	s.services = append(s.services, registeredService{desc: desc, impl: impl})
+	s.servicesMu.Unlock()
+}
+
+// RegisterServicesTo registers the services registered to the server to r, e.g. an
+// HTTP server to serve them with the gRPC-Web and Connect protocols.
+func (s *Server) RegisterServicesTo(r grpc.ServiceRegistrar) {
+	s.servicesMu.Lock()
+	services := make([]registeredService, len(s.services))
+	copy(services, s.services)
+	s.servicesMu.Unlock()
+	for _, svc := range services {
+		r.RegisterService(svc.desc, svc.impl)
+	}
+}
+
 // Endpoint return a real address to registry endpoint.
 // examples:
 //
@@ -196,6 +225,16 @@ func (s *Server) Start(ctx context.Context) error {
 	return s.Serve(s.lis)
 }
 
+// Attach prepares the server to be served by ServeHTTP on the listener of another
+// server, e.g. transport/mux, instead of Start, the requests are served with the
+// base context ctx and the health is serving as by Start, and the endpoint is the
+// one of the listener.
+func (s *Server) Attach(ctx context.Context, lis net.Listener, endpoint *url.URL) {
+	s.lis, s.endpoint = lis, endpoint
+	s.baseCtx = ctx
+	s.health.Resume()
+}
+
 // Stop stop the gRPC server.
 func (s *Server) Stop(ctx context.Context) error {
 	s.health.Shutdown()
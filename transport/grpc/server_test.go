@@ -293,3 +293,24 @@ func TestListener(t *testing.T) {
 		t.Errorf("expect not empty")
 	}
 }
+
+type testRegistrar struct {
+	services []string
+}
+
+func (r *testRegistrar) RegisterService(desc *grpc.ServiceDesc, impl interface{}) {
+	r.services = append(r.services, desc.ServiceName)
+}
+
+func TestRegisterServicesTo(t *testing.T) {
+	srv := NewServer()
+	pb.RegisterGreeterServer(srv, &server{})
+	if _, ok := srv.GetServiceInfo()["helloworld.Greeter"]; !ok {
+		t.Fatal("expected the service to be registered to the gRPC server")
+	}
+	r := &testRegistrar{}
+	srv.RegisterServicesTo(r)
+	if !reflect.DeepEqual(r.services, []string{"helloworld.Greeter"}) {
+		t.Errorf("expected [helloworld.Greeter], got %v", r.services)
+	}
+}
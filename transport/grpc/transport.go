@@ -1,12 +1,19 @@
 package grpc
 
 import (
+	"context"
+
 	"github.com/go-kratos/kratos/v2/selector"
 	"github.com/go-kratos/kratos/v2/transport"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 )
 
-var _ transport.Transporter = &Transport{}
+var (
+	_ transport.Transporter  = &Transport{}
+	_ transport.PeerProvider = &Transport{}
+)
 
 // Transport is a gRPC transport.
 type Transport struct {
@@ -15,6 +22,7 @@ type Transport struct {
 	reqHeader   headerCarrier
 	replyHeader headerCarrier
 	nodeFilters []selector.NodeFilter
+	peer        *transport.Peer
 }
 
 // Kind returns the transport kind.
@@ -42,6 +50,28 @@ func (tr *Transport) ReplyHeader() transport.Header {
 	return tr.replyHeader
 }
 
+// Peer returns the remote peer of the server transport, nil for the client transport.
+func (tr *Transport) Peer() *transport.Peer {
+	return tr.peer
+}
+
+// peerFromContext returns the remote peer of the gRPC connection, with the TLS state
+// if the connection is secured by the TLS credentials.
+func peerFromContext(ctx context.Context) *transport.Peer {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	tp := &transport.Peer{}
+	if p.Addr != nil {
+		tp.Addr = p.Addr.String()
+	}
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+		tp.TLS = &info.State
+	}
+	return tp
+}
+
 // NodeFilters returns the client select filters.
 func (tr *Transport) NodeFilters() []selector.NodeFilter {
 	return tr.nodeFilters
@@ -1,11 +1,16 @@
 package grpc
 
 import (
+	"context"
+	"crypto/tls"
+	"net"
 	"reflect"
 	"sort"
 	"testing"
 
 	"github.com/go-kratos/kratos/v2/transport"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
 )
 
 func TestTransport_Kind(t *testing.T) {
@@ -68,3 +73,19 @@ func TestHeaderCarrier_Keys(t *testing.T) {
 		t.Errorf("expect %v, got %v", want, keys)
 	}
 }
+
+func TestPeerFromContext(t *testing.T) {
+	if p := peerFromContext(context.Background()); p != nil {
+		t.Errorf("expected nil, got %+v", p)
+	}
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+	state := tls.ConnectionState{ServerName: "kratos"}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr, AuthInfo: credentials.TLSInfo{State: state}})
+	p := peerFromContext(ctx)
+	if p == nil || p.Addr != "10.0.0.1:1234" || p.TLS == nil || p.TLS.ServerName != "kratos" {
+		t.Errorf("unexpected peer: %+v", p)
+	}
+	if got := (&Transport{peer: p}).Peer(); got != p {
+		t.Errorf("expected %v, got %v", p, got)
+	}
+}
@@ -0,0 +1,253 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// AccessLogFormat is the format of the access log.
+type AccessLogFormat int
+
+const (
+	// AccessLogCombined is the Apache combined log format.
+	AccessLogCombined AccessLogFormat = iota
+	// AccessLogCommon is the Apache common log format.
+	AccessLogCommon
+	// AccessLogJSON is the JSON lines log format.
+	AccessLogJSON
+)
+
+// AccessLogOption is access log option.
+type AccessLogOption func(*accessLog)
+
+// AccessLogWriter with the writer of access log, default is os.Stdout.
+func AccessLogWriter(w io.Writer) AccessLogOption {
+	return func(o *accessLog) {
+		o.writer = w
+	}
+}
+
+// AccessLogFormatter with the format of access log, default is AccessLogCombined.
+func AccessLogFormatter(format AccessLogFormat) AccessLogOption {
+	return func(o *accessLog) {
+		o.format = format
+	}
+}
+
+// AccessLogLogger with a structured logger, the access log entries are written
+// to the logger as key-values at info level instead of the writer.
+func AccessLogLogger(logger log.Logger) AccessLogOption {
+	return func(o *accessLog) {
+		o.logger = logger
+	}
+}
+
+type accessLog struct {
+	mu     sync.Mutex
+	writer io.Writer
+	format AccessLogFormat
+	logger log.Logger
+}
+
+// AccessLogEntry is an access log entry of an HTTP request.
+type AccessLogEntry struct {
+	Time         time.Time     `json:"time"`
+	RemoteAddr   string        `json:"remote_addr"`
+	User         string        `json:"user,omitempty"`
+	Method       string        `json:"method"`
+	URI          string        `json:"uri"`
+	Proto        string        `json:"proto"`
+	PathTemplate string        `json:"path_template,omitempty"`
+	Operation    string        `json:"operation,omitempty"`
+	Status       int           `json:"status"`
+	Bytes        int64         `json:"bytes"`
+	Referer      string        `json:"referer,omitempty"`
+	UserAgent    string        `json:"user_agent,omitempty"`
+	Latency      time.Duration `json:"latency"`
+}
+
+// AccessLog returns a FilterFunc that logs the HTTP status, bytes written,
+// user agent, remote addr and path template of each request.
+// It works for both proto routes and raw handlers registered via Server.Handle,
+// when used as a server filter: http.Filter(http.AccessLog()).
+func AccessLog(opts ...AccessLogOption) FilterFunc {
+	o := &accessLog{
+		writer: os.Stdout,
+		format: AccessLogCombined,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			route := &routeInfo{}
+			if tr, ok := transport.FromServerContext(req.Context()); ok {
+				if tr, ok := tr.(*Transport); ok {
+					route.tr = tr
+				}
+			}
+			cw := &captureWriter{ResponseWriter: w}
+			next.ServeHTTP(cw, req.WithContext(context.WithValue(req.Context(), routeInfoKey{}, route)))
+			entry := &AccessLogEntry{
+				Time:       start,
+				RemoteAddr: remoteHost(req.RemoteAddr),
+				Method:     req.Method,
+				URI:        req.RequestURI,
+				Proto:      req.Proto,
+				Status:     cw.status(),
+				Bytes:      cw.bytes,
+				Referer:    req.Referer(),
+				UserAgent:  req.UserAgent(),
+				Latency:    time.Since(start),
+			}
+			if entry.URI == "" {
+				entry.URI = req.URL.RequestURI()
+			}
+			if user, _, ok := req.BasicAuth(); ok {
+				entry.User = user
+			} else if req.URL.User != nil {
+				entry.User = req.URL.User.Username()
+			}
+			if route.tr != nil {
+				entry.PathTemplate = route.tr.PathTemplate()
+				entry.Operation = route.tr.Operation()
+			}
+			o.write(entry)
+		})
+	}
+}
+
+func (o *accessLog) write(e *AccessLogEntry) {
+	if o.logger != nil {
+		_ = o.logger.Log(log.LevelInfo,
+			"kind", "access",
+			"remote_addr", e.RemoteAddr,
+			"user", e.User,
+			"method", e.Method,
+			"uri", e.URI,
+			"proto", e.Proto,
+			"path_template", e.PathTemplate,
+			"operation", e.Operation,
+			"status", e.Status,
+			"bytes", e.Bytes,
+			"referer", e.Referer,
+			"user_agent", e.UserAgent,
+			"latency", e.Latency.Seconds(),
+		)
+		return
+	}
+	var line []byte
+	switch o.format {
+	case AccessLogJSON:
+		b, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		line = append(b, '\n')
+	case AccessLogCommon:
+		line = []byte(commonLog(e) + "\n")
+	default:
+		line = []byte(fmt.Sprintf("%s %q %q\n", commonLog(e), e.Referer, e.UserAgent))
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	_, _ = o.writer.Write(line)
+}
+
+// commonLog returns the entry in Apache common log format:
+// host ident authuser [date] "request" status bytes
+func commonLog(e *AccessLogEntry) string {
+	user := e.User
+	if user == "" {
+		user = "-"
+	}
+	size := "-"
+	if e.Bytes > 0 {
+		size = fmt.Sprint(e.Bytes)
+	}
+	return fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s",
+		e.RemoteAddr, user, e.Time.Format("02/Jan/2006:15:04:05 -0700"), e.Method, e.URI, e.Proto, e.Status, size)
+}
+
+func remoteHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+type routeInfoKey struct{}
+
+// routeInfo is filled by the server with the transport of the matched route,
+// so that filters running outside the router can see the path template.
+type routeInfo struct {
+	tr *Transport
+}
+
+func setRouteInfo(ctx context.Context, tr *Transport) {
+	if route, ok := ctx.Value(routeInfoKey{}).(*routeInfo); ok {
+		route.tr = tr
+	}
+}
+
+// captureWriter records the status code and the bytes written of the response.
+type captureWriter struct {
+	http.ResponseWriter
+	code  int
+	bytes int64
+}
+
+func (w *captureWriter) WriteHeader(statusCode int) {
+	if w.code == 0 {
+		w.code = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *captureWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
+
+func (w *captureWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *captureWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.code = http.StatusSwitchingProtocols
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("http: response writer does not implement http.Hijacker")
+}
+
+// Unwrap returns the original http.ResponseWriter.
+func (w *captureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
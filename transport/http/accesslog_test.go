@@ -0,0 +1,69 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	bf := bytes.NewBuffer(nil)
+	srv := NewServer(Filter(AccessLog(AccessLogWriter(bf), AccessLogFormatter(AccessLogJSON))))
+	srv.HandleFunc("/raw/{name}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("raw"))
+	})
+	srv.Route("/").GET("/users/{id}", func(ctx Context) error {
+		return ctx.String(http.StatusOK, "hello")
+	})
+
+	tests := []struct {
+		path     string
+		template string
+		status   int
+		bytes    int64
+	}{
+		{"/raw/kratos", "/raw/{name}", http.StatusAccepted, 3},
+		{"/users/1", "/users/{id}", http.StatusOK, 5},
+	}
+	for _, test := range tests {
+		bf.Reset()
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		req.Header.Set("User-Agent", "kratos-test")
+		srv.ServeHTTP(httptest.NewRecorder(), req)
+		var entry AccessLogEntry
+		if err := json.Unmarshal(bf.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry.PathTemplate != test.template {
+			t.Errorf("expected path template %s got %s", test.template, entry.PathTemplate)
+		}
+		if entry.Status != test.status {
+			t.Errorf("expected status %d got %d", test.status, entry.Status)
+		}
+		if entry.Bytes != test.bytes {
+			t.Errorf("expected bytes %d got %d", test.bytes, entry.Bytes)
+		}
+		if entry.UserAgent != "kratos-test" || entry.RemoteAddr != "192.0.2.1" {
+			t.Errorf("unexpected entry %+v", entry)
+		}
+	}
+}
+
+func TestAccessLogCombined(t *testing.T) {
+	bf := bytes.NewBuffer(nil)
+	srv := NewServer(Filter(AccessLog(AccessLogWriter(bf))))
+	req := httptest.NewRequest(http.MethodGet, "/not-found", nil)
+	req.Header.Set("Referer", "http://example.com")
+	srv.ServeHTTP(httptest.NewRecorder(), req)
+	line := bf.String()
+	if !strings.HasPrefix(line, "192.0.2.1 - - [") {
+		t.Errorf("unexpected line %q", line)
+	}
+	if !strings.Contains(line, `"GET /not-found HTTP/1.1" 404`) || !strings.HasSuffix(line, "\"http://example.com\" \"\"\n") {
+		t.Errorf("unexpected line %q", line)
+	}
+}
@@ -1,7 +1,12 @@
 package http
 
 import (
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/encoding"
 )
 
 // CallOption configures a Call before it starts or extracts information from
@@ -17,9 +22,12 @@ type CallOption interface {
 }
 
 type callInfo struct {
-	contentType  string
-	operation    string
-	pathTemplate string
+	contentType     string
+	compressor      string
+	operation       string
+	pathTemplate    string
+	body            io.Reader
+	responseHandler func(*http.Response) error
 }
 
 // EmptyCallOption does not alter the Call configuration.
@@ -107,3 +115,57 @@ func (o HeaderCallOption) after(c *callInfo, cs *csAttempt) {
 		*o.header = cs.res.Header
 	}
 }
+
+// UseCompressor returns a CallOption which compresses the request body with the
+// compressor registered for the content-coding name, e.g. gzip.
+func UseCompressor(name string) CallOption {
+	return CompressorCallOption{CompressorType: strings.ToLower(name)}
+}
+
+// CompressorCallOption is set the request compressor for client call
+type CompressorCallOption struct {
+	EmptyCallOption
+	CompressorType string
+}
+
+func (o CompressorCallOption) before(c *callInfo) error {
+	if encoding.GetCompressor(o.CompressorType) == nil {
+		return fmt.Errorf("http: compressor is not registered for content-coding %q", o.CompressorType)
+	}
+	c.compressor = o.CompressorType
+	return nil
+}
+
+// RequestBody returns a CallOption which streams the request body from r
+// instead of encoding the args, whose content type is set by ContentType.
+func RequestBody(r io.Reader) CallOption {
+	return RequestBodyCallOption{Body: r}
+}
+
+// RequestBodyCallOption is set the request body stream for client call
+type RequestBodyCallOption struct {
+	EmptyCallOption
+	Body io.Reader
+}
+
+func (o RequestBodyCallOption) before(c *callInfo) error {
+	c.body = o.Body
+	return nil
+}
+
+// ResponseHandler returns a CallOption which handles the response body as a stream
+// instead of decoding it into the reply, the body is closed after fn returns.
+func ResponseHandler(fn func(*http.Response) error) CallOption {
+	return ResponseHandlerCallOption{Handler: fn}
+}
+
+// ResponseHandlerCallOption is set the response handler for client call
+type ResponseHandlerCallOption struct {
+	EmptyCallOption
+	Handler func(*http.Response) error
+}
+
+func (o ResponseHandlerCallOption) before(c *callInfo) error {
+	c.responseHandler = o.Handler
+	return nil
+}
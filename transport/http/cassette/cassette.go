@@ -0,0 +1,298 @@
+// Package cassette records the HTTP interactions of the transport/http.Client
+// into a cassette file, and replays them deterministically, so that the tests
+// of the services can run offline without the upstream services.
+//
+//	rec, err := cassette.New("testdata/greeter.json", cassette.WithMode(cassette.ModeRecord))
+//	client, err := http.NewClient(ctx, http.WithEndpoint("127.0.0.1:8000"), http.WithTransport(rec))
+//	...
+//	err = rec.Save()
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// Mode is the mode of the Recorder.
+type Mode int
+
+const (
+	// ModeReplay replays the recorded interactions, and fails the unmatched requests.
+	ModeReplay Mode = iota
+	// ModeRecord sends the requests to the upstream services and records them.
+	ModeRecord
+	// ModeReplayOrRecord replays the recorded interactions, and records the unmatched requests.
+	ModeReplayOrRecord
+)
+
+const bodyEncodingBase64 = "base64"
+
+// Option is a Recorder option.
+type Option func(*Recorder)
+
+// WithMode with the mode of the recorder, default ModeReplay.
+func WithMode(mode Mode) Option {
+	return func(r *Recorder) {
+		r.mode = mode
+	}
+}
+
+// WithTransport with the transport which sends the requests to be recorded,
+// default http.DefaultTransport.
+func WithTransport(next http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.next = next
+	}
+}
+
+// WithFilter with the filter of the interactions before they are recorded,
+// e.g. to redact the tokens in the response headers.
+func WithFilter(f func(*Interaction)) Option {
+	return func(r *Recorder) {
+		r.filters = append(r.filters, f)
+	}
+}
+
+// Request is the recorded request.
+type Request struct {
+	Method       string      `json:"method"`
+	URL          string      `json:"url"`
+	PathTemplate string      `json:"path_template,omitempty"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// Response is the recorded response.
+type Response struct {
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// Interaction is a recorded pair of the request and response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper which records and replays the interactions.
+type Recorder struct {
+	path    string
+	mode    Mode
+	next    http.RoundTripper
+	filters []func(*Interaction)
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// New returns a Recorder of the cassette file, which is loaded if it exists.
+func New(path string, opts ...Option) (*Recorder, error) {
+	r := &Recorder{path: path, next: http.DefaultTransport}
+	for _, o := range opts {
+		o(r)
+	}
+	if r.mode == ModeRecord {
+		return r, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if r.mode == ModeReplayOrRecord && errors.Is(err, os.ErrNotExist) {
+			return r, nil
+		}
+		return nil, err
+	}
+	var c cassette
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("cassette: invalid cassette %s: %w", path, err)
+	}
+	r.interactions = c.Interactions
+	r.used = make([]bool, len(c.Interactions))
+	return r, nil
+}
+
+// RoundTrip replays the matched interaction, or sends the request and records it.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := newRequest(req, body)
+	if r.mode != ModeRecord {
+		if i := r.match(recorded); i != nil {
+			return i.Response.response(req)
+		}
+		if r.mode == ModeReplay {
+			return nil, fmt.Errorf("cassette: no interaction recorded for %s %s", recorded.Method, recorded.PathTemplate)
+		}
+	}
+	res, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(data))
+	i := &Interaction{Request: recorded, Response: Response{StatusCode: res.StatusCode, Header: res.Header.Clone()}}
+	i.Response.Body, i.Response.BodyEncoding = encodeBody(data)
+	for _, f := range r.filters {
+		f(i)
+	}
+	r.mu.Lock()
+	r.interactions = append(r.interactions, i)
+	r.used = append(r.used, true)
+	r.mu.Unlock()
+	return res, nil
+}
+
+// Save writes the interactions into the cassette file, it does nothing in ModeReplay.
+func (r *Recorder) Save() error {
+	if r.mode == ModeReplay {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(cassette{Interactions: r.interactions}, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0o644) //nolint:gosec
+}
+
+// match returns the interaction matched by the method, path template and body,
+// the ones of the same URL are preferred, and each one is replayed once in order
+// of recording, except the last matched one which is replayed repeatedly.
+func (r *Recorder) match(req Request) *Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	body := canonicalBody(req)
+	first, last := -1, -1
+	for i, v := range r.interactions {
+		if v.Request.Method != req.Method || v.Request.PathTemplate != req.PathTemplate || !bytes.Equal(canonicalBody(v.Request), body) {
+			continue
+		}
+		last = i
+		if r.used[i] {
+			continue
+		}
+		if v.Request.URL == req.URL {
+			first = i
+			break
+		}
+		if first < 0 {
+			first = i
+		}
+	}
+	if first < 0 {
+		if last < 0 {
+			return nil
+		}
+		return r.interactions[last]
+	}
+	r.used[first] = true
+	return r.interactions[first]
+}
+
+func newRequest(req *http.Request, body []byte) Request {
+	pathTemplate := req.URL.Path
+	if tr, ok := transport.FromClientContext(req.Context()); ok {
+		if pt, ok := tr.(interface{ PathTemplate() string }); ok && pt.PathTemplate() != "" {
+			pathTemplate = pt.PathTemplate()
+		}
+	}
+	r := Request{Method: req.Method, URL: req.URL.RequestURI(), PathTemplate: pathTemplate}
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		// the other headers are not recorded, which may contain the credentials.
+		r.Header = http.Header{"Content-Type": {ct}}
+	}
+	r.Body, r.BodyEncoding = encodeBody(body)
+	return r
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	data, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+func (r Response) response(req *http.Request) (*http.Response, error) {
+	body, err := decodeBody(r.Body, r.BodyEncoding)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func encodeBody(data []byte) (string, string) {
+	if utf8.Valid(data) {
+		return string(data), ""
+	}
+	return base64.StdEncoding.EncodeToString(data), bodyEncodingBase64
+}
+
+func decodeBody(body, encoding string) ([]byte, error) {
+	if encoding == bodyEncodingBase64 {
+		return base64.StdEncoding.DecodeString(body)
+	}
+	return []byte(body), nil
+}
+
+// canonicalBody returns the body of which the JSON is canonicalized,
+// so that the order of the fields doesn't matter.
+func canonicalBody(req Request) []byte {
+	data, err := decodeBody(req.Body, req.BodyEncoding)
+	if err != nil {
+		return nil
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if dec.Decode(&v) != nil || dec.More() {
+		return data
+	}
+	if c, err := json.Marshal(v); err == nil {
+		return c
+	}
+	return data
+}
@@ -0,0 +1,128 @@
+package cassette
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+)
+
+func newClient(t *testing.T, endpoint string, rec *Recorder) *khttp.Client {
+	client, err := khttp.NewClient(context.Background(), khttp.WithEndpoint(endpoint), khttp.WithTransport(rec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func sayHello(client *khttp.Client, name string) (string, error) {
+	reply := make(map[string]string)
+	err := client.Invoke(context.Background(), http.MethodPost, "/v1/greeter/"+name, map[string]string{"name": name, "lang": "en"},
+		&reply, khttp.PathTemplate("/v1/greeter/{name}"))
+	return reply["message"], err
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/v1/binary" {
+			_, _ = w.Write([]byte{0xff, 0xfe, 0x00})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Token", "secret")
+		_, _ = w.Write([]byte(`{"message":"hello ` + strings.TrimPrefix(r.URL.Path, "/v1/greeter/") + `"}`))
+	}))
+	endpoint := strings.TrimPrefix(srv.URL, "http://")
+	path := filepath.Join(t.TempDir(), "testdata", "greeter.json")
+
+	rec, err := New(path, WithMode(ModeRecord), WithFilter(func(i *Interaction) {
+		i.Response.Header.Del("X-Token")
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newClient(t, endpoint, rec)
+	for _, name := range []string{"kratos", "go"} {
+		if msg, err := sayHello(client, name); err != nil || msg != "hello "+name {
+			t.Fatalf("unexpected reply: %s %v", msg, err)
+		}
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/binary", nil)
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if err = rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	// the interactions are replayed without the upstream service.
+	rec, err = New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = newClient(t, endpoint, rec)
+	for _, name := range []string{"go", "kratos", "kratos"} {
+		if msg, err := sayHello(client, name); err != nil || msg != "hello "+name {
+			t.Errorf("unexpected reply: %s %v", msg, err)
+		}
+	}
+	// the body is matched, and the unmatched request fails.
+	if err = client.Invoke(context.Background(), http.MethodPost, "/v1/greeter/kratos", map[string]string{"name": "other"},
+		nil, khttp.PathTemplate("/v1/greeter/{name}")); err == nil || !strings.Contains(err.Error(), "no interaction recorded") {
+		t.Errorf("expected the unmatched error, got %v", err)
+	}
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/v1/binary", nil)
+	if res, err = client.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	if string(data) != string([]byte{0xff, 0xfe, 0x00}) || res.Header.Get("X-Token") != "" {
+		t.Errorf("unexpected response: %v %v", data, res.Header)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestReplayOrRecord(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		_, _ = w.Write([]byte(`{"message":"hello"}`))
+	}))
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "greeter.json")
+	if _, err := New(path); err == nil {
+		t.Error("expected an error of the missing cassette")
+	}
+	for i := 0; i < 2; i++ {
+		rec, err := New(path, WithMode(ModeReplayOrRecord))
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := newClient(t, strings.TrimPrefix(srv.URL, "http://"), rec)
+		for j := 0; j < 2; j++ {
+			if msg, err := sayHello(client, "kratos"); err != nil || msg != "hello" {
+				t.Fatalf("unexpected reply: %s %v", msg, err)
+			}
+		}
+		if err = rec.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
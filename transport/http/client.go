@@ -11,13 +11,18 @@ import (
 
 	"github.com/go-kratos/kratos/v2/encoding"
 	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/internal/endpoint"
 	"github.com/go-kratos/kratos/v2/internal/host"
 	"github.com/go-kratos/kratos/v2/internal/httputil"
+	ireply "github.com/go-kratos/kratos/v2/internal/reply"
+	"github.com/go-kratos/kratos/v2/metrics"
 	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/go-kratos/kratos/v2/registry"
 	"github.com/go-kratos/kratos/v2/selector"
 	"github.com/go-kratos/kratos/v2/selector/p2c"
 	"github.com/go-kratos/kratos/v2/transport"
+
+	"google.golang.org/genproto/googleapis/api/httpbody"
 )
 
 func init() {
@@ -53,6 +58,20 @@ type clientOptions struct {
 	discovery    registry.Discovery
 	middleware   []middleware.Middleware
 	block        bool
+	h2c          bool
+
+	maxIdleConnsPerHost int
+	maxConnsPerHost     int
+	idleConnTimeout     time.Duration
+	dialTimeout         time.Duration
+	keepAlive           time.Duration
+	tlsHandshakeTimeout time.Duration
+	inflight            metrics.Gauge
+	connections         metrics.Gauge
+
+	proxy   string
+	noProxy []string
+	socket  string
 }
 
 // WithTransport with client transport.
@@ -62,7 +81,10 @@ func WithTransport(trans http.RoundTripper) ClientOption {
 	}
 }
 
-// WithTimeout with client request timeout.
+// WithTimeout with client request timeout, default 2s, which limits the whole call
+// including reading the response body. The calls with the RequestBody or the
+// ResponseHandler call options are not limited by it, since the bodies are streamed
+// for an unknown time, whose ctx must be canceled to stop them.
 func WithTimeout(d time.Duration) ClientOption {
 	return func(o *clientOptions) {
 		o.timeout = d
@@ -83,7 +105,8 @@ func WithMiddleware(m ...middleware.Middleware) ClientOption {
 	}
 }
 
-// WithEndpoint with client addr.
+// WithEndpoint with client addr, e.g. 127.0.0.1:8000, discovery:///helloworld,
+// or unix:///var/run/helloworld.sock for the local sidecars.
 func WithEndpoint(endpoint string) ClientOption {
 	return func(o *clientOptions) {
 		o.endpoint = endpoint
@@ -139,12 +162,21 @@ func WithTLSConfig(c *tls.Config) ClientOption {
 	}
 }
 
+// WithH2C with HTTP/2 cleartext, the client talks HTTP/2 with prior knowledge
+// to the servers without TLS. It can't be used with WithTransport, and has no effect with TLS.
+func WithH2C() ClientOption {
+	return func(o *clientOptions) {
+		o.h2c = true
+	}
+}
+
 // Client is an HTTP client.
 type Client struct {
 	opts     clientOptions
 	target   *Target
 	r        *resolver
 	cc       *http.Client
+	stream   *http.Client // the client of the streaming calls without the timeout
 	insecure bool
 	selector selector.Selector
 }
@@ -157,21 +189,26 @@ func NewClient(ctx context.Context, opts ...ClientOption) (*Client, error) {
 		encoder:      DefaultRequestEncoder,
 		decoder:      DefaultResponseDecoder,
 		errorDecoder: DefaultErrorDecoder,
-		transport:    http.DefaultTransport,
 	}
 	for _, o := range opts {
 		o(&options)
 	}
-	if options.tlsConf != nil {
-		if tr, ok := options.transport.(*http.Transport); ok {
-			tr.TLSClientConfig = options.tlsConf
-		}
-	}
 	insecure := options.tlsConf == nil
 	target, err := parseTarget(options.endpoint, insecure)
 	if err != nil {
 		return nil, err
 	}
+	if target.Scheme == "unix" {
+		if options.discovery != nil {
+			return nil, fmt.Errorf("[http client] the unix socket endpoint %s can't be resolved by the discovery", options.endpoint)
+		}
+		// all of the requests are sent to the socket whatever the host is.
+		options.socket = target.Endpoint
+		target = &Target{Scheme: endpoint.Scheme("http", !insecure), Authority: "localhost"}
+	}
+	if options.transport, err = options.buildTransport(); err != nil {
+		return nil, err
+	}
 	selector := selector.GlobalSelector().Build()
 	var r *resolver
 	if options.discovery != nil {
@@ -192,6 +229,9 @@ func NewClient(ctx context.Context, opts ...ClientOption) (*Client, error) {
 			Timeout:   options.timeout,
 			Transport: options.transport,
 		},
+		stream: &http.Client{
+			Transport: options.transport,
+		},
 		selector: selector,
 	}, nil
 }
@@ -208,12 +248,30 @@ func (client *Client) Invoke(ctx context.Context, method, path string, args inte
 			return err
 		}
 	}
-	if args != nil {
+	if c.body != nil {
+		contentType = c.contentType
+		body = c.body
+		if c.compressor != "" {
+			pr := compressReader(c.compressor, body)
+			// the goroutine compressing the body exits once the pipe is closed,
+			// even if the request fails before the body is read.
+			defer pr.Close()
+			body = pr
+		}
+	} else if args != nil {
 		data, err := client.opts.encoder(ctx, c.contentType, args)
 		if err != nil {
 			return err
 		}
+		if c.compressor != "" {
+			if data, err = compressData(c.compressor, data); err != nil {
+				return err
+			}
+		}
 		contentType = c.contentType
+		if hb, ok := args.(*httpbody.HttpBody); ok && hb.ContentType != "" {
+			contentType = hb.ContentType
+		}
 		body = bytes.NewReader(data)
 	}
 	url := fmt.Sprintf("%s://%s%s", client.target.Scheme, client.target.Authority, path)
@@ -222,7 +280,10 @@ func (client *Client) Invoke(ctx context.Context, method, path string, args inte
 		return err
 	}
 	if contentType != "" {
-		req.Header.Set("Content-Type", c.contentType)
+		req.Header.Set("Content-Type", contentType)
+		if c.compressor != "" {
+			req.Header.Set("Content-Encoding", c.compressor)
+		}
 	}
 	if client.opts.userAgent != "" {
 		req.Header.Set("User-Agent", client.opts.userAgent)
@@ -239,7 +300,7 @@ func (client *Client) Invoke(ctx context.Context, method, path string, args inte
 
 func (client *Client) invoke(ctx context.Context, req *http.Request, args interface{}, reply interface{}, c callInfo, opts ...CallOption) error {
 	h := func(ctx context.Context, in interface{}) (interface{}, error) {
-		res, err := client.do(req.WithContext(ctx))
+		res, err := client.do(req.WithContext(ctx), c.body != nil || c.responseHandler != nil)
 		if res != nil {
 			cs := csAttempt{res: res}
 			for _, o := range opts {
@@ -250,7 +311,12 @@ func (client *Client) invoke(ctx context.Context, req *http.Request, args interf
 			return nil, err
 		}
 		defer res.Body.Close()
-		if err := client.opts.decoder(ctx, res, reply); err != nil {
+		if c.responseHandler != nil {
+			err = c.responseHandler(res)
+		} else {
+			err = client.opts.decoder(ctx, res, reply)
+		}
+		if err != nil {
 			return nil, err
 		}
 		return reply, nil
@@ -260,12 +326,20 @@ func (client *Client) invoke(ctx context.Context, req *http.Request, args interf
 	if len(client.opts.middleware) > 0 {
 		h = middleware.Chain(client.opts.middleware...)(h)
 	}
-	_, err := h(ctx, args)
-	return err
+	res, err := h(ctx, args)
+	if err != nil {
+		return err
+	}
+	// the middleware may answer without calling the server, e.g. from a cache.
+	ireply.Copy(reply, res)
+	return nil
 }
 
 // Do send an HTTP request and decodes the body of response into target.
 // returns an error (of type *Error) if the response status code is not 2xx.
+// The request runs through the client middleware as Invoke does, whose
+// operation is the request path unless the Operation call option is set, and
+// whose reply must be the *http.Response.
 func (client *Client) Do(req *http.Request, opts ...CallOption) (*http.Response, error) {
 	c := defaultCallInfo(req.URL.Path)
 	for _, o := range opts {
@@ -273,11 +347,109 @@ func (client *Client) Do(req *http.Request, opts ...CallOption) (*http.Response,
 			return nil, err
 		}
 	}
+	if c.compressor != "" && req.Body != nil && req.Body != http.NoBody && req.Header.Get("Content-Encoding") == "" {
+		data, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if data, err = compressData(c.compressor, data); err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}
+		req.ContentLength = int64(len(data))
+		req.Header.Set("Content-Encoding", c.compressor)
+	}
+	ctx := transport.NewClientContext(req.Context(), &Transport{
+		endpoint:     client.opts.endpoint,
+		reqHeader:    headerCarrier(req.Header),
+		operation:    c.operation,
+		request:      req,
+		pathTemplate: c.pathTemplate,
+	})
+	var last *http.Response
+	h := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		// the middleware may call the server again, e.g. to retry,
+		// so the response of the previous attempt is released.
+		if last != nil {
+			_ = last.Body.Close()
+			last = nil
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = body
+			}
+		}
+		res, err := client.do(req.WithContext(ctx), false)
+		if res != nil {
+			last = res
+			cs := csAttempt{res: res}
+			for _, o := range opts {
+				o.after(&c, &cs)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+	var p selector.Peer
+	ctx = selector.NewPeerContext(ctx, &p)
+	if len(client.opts.middleware) > 0 {
+		h = middleware.Chain(client.opts.middleware...)(h)
+	}
+	reply, err := h(ctx, nil)
+	// the middleware may answer without calling the server, e.g. from a cache.
+	res, ok := reply.(*http.Response)
+	if last != nil && (err != nil || last != res) {
+		_ = last.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok || res == nil {
+		return nil, fmt.Errorf("[http client] the middleware replied %T instead of *http.Response", reply)
+	}
+	return res, nil
+}
 
-	return client.do(req)
+func compressData(name string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := encoding.GetCompressor(name).Compress(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// compressReader returns a reader of the data of r compressed by the compressor.
+func compressReader(name string, r io.Reader) *io.PipeReader {
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := encoding.GetCompressor(name).Compress(pw)
+		if err == nil {
+			_, err = io.Copy(w, r)
+			if cerr := w.Close(); err == nil {
+				err = cerr
+			}
+		}
+		_ = pw.CloseWithError(err)
+	}()
+	return pr
 }
 
-func (client *Client) do(req *http.Request) (*http.Response, error) {
+func (client *Client) do(req *http.Request, stream bool) (*http.Response, error) {
 	var done func(context.Context, selector.DoneInfo)
 	if client.r != nil {
 		var (
@@ -294,13 +466,31 @@ func (client *Client) do(req *http.Request) (*http.Response, error) {
 		}
 		req.URL.Host = node.Address()
 		req.Host = node.Address()
+		if _, ok := unixSocket(node.Address()); ok {
+			req.Host = "localhost"
+		}
+	}
+	cc := client.cc
+	if stream {
+		cc = client.stream
+	}
+	resp, err := cc.Do(req)
+	if resp != nil {
+		if tr, ok := transport.FromClientContext(req.Context()); ok {
+			if ht, ok := tr.(*Transport); ok {
+				ht.replyHeader = headerCarrier(resp.Header)
+			}
+		}
 	}
-	resp, err := client.cc.Do(req)
 	if err == nil {
 		err = client.opts.errorDecoder(req.Context(), resp)
 	}
 	if done != nil {
-		done(req.Context(), selector.DoneInfo{Err: err})
+		di := selector.DoneInfo{Err: err, BytesSent: resp != nil, BytesReceived: resp != nil}
+		if resp != nil {
+			di.ReplyMD = headerCarrier(resp.Header)
+		}
+		done(req.Context(), di)
 	}
 	if err != nil {
 		return nil, err
@@ -310,6 +500,7 @@ func (client *Client) do(req *http.Request) (*http.Response, error) {
 
 // Close tears down the Transport and all underlying connections.
 func (client *Client) Close() error {
+	client.cc.CloseIdleConnections()
 	if client.r != nil {
 		return client.r.Close()
 	}
@@ -318,6 +509,9 @@ func (client *Client) Close() error {
 
 // DefaultRequestEncoder is an HTTP request encoder.
 func DefaultRequestEncoder(ctx context.Context, contentType string, in interface{}) ([]byte, error) {
+	if hb, ok := in.(*httpbody.HttpBody); ok {
+		return hb.Data, nil
+	}
 	name := httputil.ContentSubtype(contentType)
 	body, err := encoding.GetCodec(name).Marshal(in)
 	if err != nil {
@@ -333,24 +527,46 @@ func DefaultResponseDecoder(ctx context.Context, res *http.Response, v interface
 	if err != nil {
 		return err
 	}
+	if hb, ok := v.(*httpbody.HttpBody); ok {
+		hb.ContentType = res.Header.Get("Content-Type")
+		hb.Data = data
+		return nil
+	}
 	return CodecForResponse(res).Unmarshal(data, v)
 }
 
-// DefaultErrorDecoder is an HTTP error decoder.
+// DefaultErrorDecoder is an HTTP error decoder, which decodes the kratos error from the body,
+// or the error of the status code with the message of the text error page, e.g. of the proxies,
+// and the gateway errors are given the retryable reasons. The Retry-After and X-Request-Id
+// response headers are added to the metadata of the error.
 func DefaultErrorDecoder(ctx context.Context, res *http.Response) error {
+	return decodeError(res, defaultErrorHeaders)
+}
+
+// NewErrorDecoder returns an error decoder as DefaultErrorDecoder, which adds the given
+// response headers to the metadata of the error instead.
+func NewErrorDecoder(headers ...string) DecodeErrorFunc {
+	return func(ctx context.Context, res *http.Response) error {
+		return decodeError(res, headers)
+	}
+}
+
+func decodeError(res *http.Response, headers []string) error {
 	if res.StatusCode >= 200 && res.StatusCode <= 299 {
 		return nil
 	}
 	defer res.Body.Close()
-	data, err := io.ReadAll(res.Body)
-	if err == nil {
-		e := new(errors.Error)
-		if err = CodecForResponse(res).Unmarshal(data, e); err == nil {
-			e.Code = int32(res.StatusCode)
-			return e
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+	e := decodeErrorBody(res, data)
+	if e == nil {
+		e = errors.New(res.StatusCode, gatewayReason(res.StatusCode), errorMessage(res.StatusCode, data))
+		if err != nil {
+			e = e.WithCause(err)
 		}
 	}
-	return errors.Newf(res.StatusCode, errors.UnknownReason, "").WithCause(err)
+	e.Code = int32(res.StatusCode)
+	e.Metadata = headerMetadata(e.Metadata, res.Header, headers)
+	return e
 }
 
 // CodecForResponse get encoding.Codec via http.Response
@@ -10,15 +10,19 @@ import (
 	"io"
 	"log"
 	nethttp "net/http"
+	"net/http/httptest"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/go-kratos/kratos/v2/encoding"
 	kratosErrors "github.com/go-kratos/kratos/v2/errors"
 	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/go-kratos/kratos/v2/registry"
 	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/transport"
 )
 
 type mockRoundTripper struct{}
@@ -364,3 +368,269 @@ func TestNewClient(t *testing.T) {
 		t.Error("err should be equal to encoder error")
 	}
 }
+
+type mockSelector struct {
+	node selector.Node
+	done selector.DoneInfo
+}
+
+func (s *mockSelector) Apply([]selector.Node) {}
+
+func (s *mockSelector) Select(ctx context.Context, _ ...selector.SelectOption) (selector.Node, selector.DoneFunc, error) {
+	if p, ok := selector.FromPeerContext(ctx); ok {
+		p.Node = s.node
+	}
+	return s.node, func(_ context.Context, di selector.DoneInfo) { s.done = di }, nil
+}
+
+func TestDoMiddleware(t *testing.T) {
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Header().Set("X-Load", "1")
+		_, _ = w.Write([]byte(r.Header.Get("X-Trace")))
+	}))
+	defer srv.Close()
+
+	var (
+		operation, pathTemplate, method, load string
+		peer                                  *selector.Peer
+	)
+	client, err := NewClient(context.Background(), WithEndpoint("127.0.0.1"), WithMiddleware(func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, _ := transport.FromClientContext(ctx)
+			ht := tr.(Transporter)
+			operation, pathTemplate, method = tr.Operation(), ht.PathTemplate(), ht.Request().Method
+			tr.RequestHeader().Set("X-Trace", "trace-id")
+			reply, err := handler(ctx, req)
+			load = tr.ReplyHeader().Get("X-Load")
+			peer, _ = selector.FromPeerContext(ctx)
+			return reply, err
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := &mockSelector{node: selector.NewNode("http", strings.TrimPrefix(srv.URL, "http://"), nil)}
+	client.selector = ms
+	client.r = &resolver{}
+	client.insecure = true
+
+	req, _ := nethttp.NewRequest(nethttp.MethodPost, "http://discovery/v1/users/1", nil)
+	res, err := client.Do(req, Operation("/users.v1.User/Get"), PathTemplate("/v1/users/{id}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != "trace-id" {
+		t.Errorf("expected the header set by middleware, got %q", body)
+	}
+	if operation != "/users.v1.User/Get" || pathTemplate != "/v1/users/{id}" || method != nethttp.MethodPost || load != "1" {
+		t.Errorf("unexpected transport: %s %s %s %s", operation, pathTemplate, method, load)
+	}
+	if peer == nil || peer.Node != ms.node {
+		t.Errorf("expected the selected peer, got %+v", peer)
+	}
+	if ms.done.ReplyMD == nil || ms.done.ReplyMD.Get("X-Load") != "1" || !ms.done.BytesReceived {
+		t.Errorf("unexpected done info: %+v", ms.done)
+	}
+}
+
+type closeBody struct {
+	io.Reader
+	closed *int
+}
+
+func (b closeBody) Close() error {
+	*b.closed++
+	return nil
+}
+
+type closeRoundTripper struct {
+	bodies []string
+	closed int
+}
+
+func (rt *closeRoundTripper) RoundTrip(req *nethttp.Request) (*nethttp.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	rt.bodies = append(rt.bodies, string(body))
+	return &nethttp.Response{StatusCode: 200, Header: nethttp.Header{}, Body: closeBody{Reader: strings.NewReader("ok"), closed: &rt.closed}}, nil
+}
+
+func TestDoMiddlewareReply(t *testing.T) {
+	tests := []struct {
+		name   string
+		m      middleware.Middleware
+		calls  int
+		closed int
+		err    bool
+	}{
+		{"retry", func(handler middleware.Handler) middleware.Handler {
+			return func(ctx context.Context, req interface{}) (interface{}, error) {
+				_, _ = handler(ctx, req)
+				return handler(ctx, req)
+			}
+		}, 2, 1, false},
+		{"cached", func(handler middleware.Handler) middleware.Handler {
+			return func(ctx context.Context, req interface{}) (interface{}, error) {
+				return &nethttp.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("cached"))}, nil
+			}
+		}, 0, 0, false},
+		{"nil reply", func(handler middleware.Handler) middleware.Handler {
+			return func(ctx context.Context, req interface{}) (interface{}, error) {
+				_, _ = handler(ctx, req)
+				return nil, nil
+			}
+		}, 1, 1, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rt := &closeRoundTripper{}
+			client, err := NewClient(context.Background(), WithEndpoint("127.0.0.1"), WithTransport(rt), WithMiddleware(test.m))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ := nethttp.NewRequest(nethttp.MethodPost, "http://127.0.0.1/v1/users", strings.NewReader("body"))
+			res, err := client.Do(req)
+			if (err != nil) != test.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(rt.bodies) != test.calls || rt.closed != test.closed {
+				t.Errorf("expected %d calls and %d closed, got %d %d", test.calls, test.closed, len(rt.bodies), rt.closed)
+			}
+			for _, body := range rt.bodies {
+				if body != "body" {
+					t.Errorf("expected the body of every attempt, got %q", body)
+				}
+			}
+			if res != nil {
+				_ = res.Body.Close()
+			}
+		})
+	}
+}
+
+func TestStreamingBody(t *testing.T) {
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		body, _ := decompressBody(r)
+		defer body.Close()
+		d := NewNDJSONDecoder(body)
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for {
+			var v map[string]int
+			if err := d.Decode(&v); err != nil {
+				break
+			}
+			v["n"] *= 10
+			data, _ := json.Marshal(v)
+			_, _ = w.Write(append(data, '\n'))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), WithEndpoint(strings.TrimPrefix(srv.URL, "http://")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		for i := 1; i <= 3; i++ {
+			_, _ = fmt.Fprintf(pw, "{\"n\":%d}\n", i)
+		}
+		_ = pw.Close()
+	}()
+	var sum int
+	err = client.Invoke(context.Background(), nethttp.MethodPost, "/export", nil, nil,
+		RequestBody(pr), ContentType("application/x-ndjson"), UseCompressor("gzip"),
+		ResponseHandler(func(res *nethttp.Response) error {
+			d := NewNDJSONDecoder(res.Body)
+			for {
+				var v map[string]int
+				if err := d.Decode(&v); err == io.EOF {
+					return nil
+				} else if err != nil {
+					return err
+				}
+				sum += v["n"]
+			}
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != 60 {
+		t.Errorf("expected 60, got %d", sum)
+	}
+}
+
+// closeCompressor is a compressor without compression, which reports the close of the writers.
+type closeCompressor struct {
+	closed chan struct{}
+}
+
+type closeWriter struct {
+	io.Writer
+	closed chan struct{}
+}
+
+func (w closeWriter) Close() error {
+	close(w.closed)
+	return nil
+}
+
+func (c closeCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return closeWriter{Writer: w, closed: c.closed}, nil
+}
+func (c closeCompressor) Decompress(r io.Reader) (io.Reader, error) { return r, nil }
+func (c closeCompressor) Name() string                              { return "close-test" }
+
+func TestStreamingBodyFailed(t *testing.T) {
+	c := closeCompressor{closed: make(chan struct{})}
+	encoding.RegisterCompressor(c)
+	failed := errors.New("failed")
+	client, err := NewClient(context.Background(),
+		WithEndpoint("127.0.0.1:1"),
+		WithMiddleware(func(middleware.Handler) middleware.Handler {
+			return func(context.Context, interface{}) (interface{}, error) {
+				return nil, failed
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the body is larger than the pipe, which is never read by the failed request.
+	body := io.MultiReader(strings.NewReader("{}"), bytes.NewReader(make([]byte, 1<<20)))
+	err = client.Invoke(context.Background(), nethttp.MethodPost, "/upload", nil, nil, RequestBody(body), UseCompressor(c.Name()))
+	if !errors.Is(err, failed) {
+		t.Fatalf("expected the error of the middleware, got %v", err)
+	}
+	select {
+	case <-c.closed:
+	case <-time.After(time.Second):
+		t.Error("expected the compressing goroutine to exit")
+	}
+}
+
+func TestStreamingTimeout(t *testing.T) {
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		for i := 0; i < 3; i++ {
+			_, _ = w.Write([]byte("{}\n"))
+			w.(nethttp.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+	client, err := NewClient(context.Background(), WithEndpoint(strings.TrimPrefix(srv.URL, "http://")), WithTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.Invoke(context.Background(), nethttp.MethodGet, "/events", nil, nil, ResponseHandler(func(res *nethttp.Response) error {
+		_, err := io.Copy(io.Discard, res.Body)
+		return err
+	}))
+	if err != nil {
+		t.Errorf("expected the streaming call not limited by the timeout, got %v", err)
+	}
+	if err = client.Invoke(context.Background(), nethttp.MethodGet, "/events", nil, &map[string]string{}); err == nil {
+		t.Error("expected the call limited by the timeout")
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/go-kratos/kratos/v2/encoding"
 	"github.com/go-kratos/kratos/v2/errors"
 	"github.com/go-kratos/kratos/v2/internal/httputil"
+	"google.golang.org/genproto/googleapis/api/httpbody"
 )
 
 // SupportPackageIsVersion1 These constants should not be referenced from any other code.
@@ -39,13 +41,21 @@ type EncodeErrorFunc func(http.ResponseWriter, *http.Request, error)
 
 // DefaultRequestDecoder decodes the request body to object.
 func DefaultRequestDecoder(r *http.Request, v interface{}) error {
+	if hb, ok := v.(*httpbody.HttpBody); ok {
+		return decodeHTTPBody(r, hb)
+	}
 	codec, ok := CodecForRequest(r, "Content-Type")
 	if !ok {
 		return errors.BadRequest("CODEC", fmt.Sprintf("unregister Content-Type: %s", r.Header.Get("Content-Type")))
 	}
-	data, err := io.ReadAll(r.Body)
+	body, err := decompressBody(r)
 	if err != nil {
-		return errors.BadRequest("CODEC", err.Error())
+		return err
+	}
+	defer body.Close()
+	data, err := readBody(body)
+	if err != nil {
+		return err
 	}
 	if len(data) == 0 {
 		return nil
@@ -56,6 +66,84 @@ func DefaultRequestDecoder(r *http.Request, v interface{}) error {
 	return nil
 }
 
+// decodeHTTPBody decodes the raw request body to google.api.HttpBody.
+func decodeHTTPBody(r *http.Request, hb *httpbody.HttpBody) error {
+	body, err := decompressBody(r)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	data, err := readBody(body)
+	if err != nil {
+		return err
+	}
+	hb.ContentType = r.Header.Get("Content-Type")
+	hb.Data = data
+	return nil
+}
+
+func readBody(body io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		if se := new(errors.Error); errors.As(err, &se) {
+			return nil, se
+		}
+		return nil, errors.BadRequest("CODEC", err.Error())
+	}
+	return data, nil
+}
+
+// decompressBody returns the request body decoded according to the Content-Encoding,
+// the codings are applied in the order they are listed, and the returned body must be
+// closed to release the decompressors, which doesn't close the request body.
+func decompressBody(r *http.Request) (io.ReadCloser, error) {
+	body := &decompressedBody{Reader: r.Body}
+	codings := strings.Split(r.Header.Get("Content-Encoding"), ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		coding := strings.ToLower(strings.TrimSpace(codings[i]))
+		if coding == "" || coding == "identity" {
+			continue
+		}
+		compressor := encoding.GetCompressor(coding)
+		if compressor == nil {
+			_ = body.Close()
+			return nil, errors.New(http.StatusUnsupportedMediaType, "CODEC", fmt.Sprintf("unregister Content-Encoding: %s", coding))
+		}
+		dr, err := compressor.Decompress(body.Reader)
+		if err != nil {
+			_ = body.Close()
+			return nil, errors.BadRequest("CODEC", fmt.Sprintf("body decompress %s", err.Error()))
+		}
+		if c, ok := dr.(io.Closer); ok {
+			body.closers = append(body.closers, c)
+		}
+		body.Reader = dr
+	}
+	// the decompressed body is limited as well as the compressed one.
+	if lr, ok := r.Body.(*maxBytesReader); ok && body.Reader != r.Body {
+		body.Reader = &maxBytesReader{r: body.Reader, n: lr.limit, limit: lr.limit}
+	}
+	return body, nil
+}
+
+// decompressedBody is the decompressed request body, which closes the decompressors
+// in the reverse order of their creation.
+type decompressedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (b *decompressedBody) Close() error {
+	var err error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		if e := b.closers[i].Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	b.closers = nil
+	return err
+}
+
 // DefaultResponseEncoder encodes the object to the HTTP response.
 func DefaultResponseEncoder(w http.ResponseWriter, r *http.Request, v interface{}) error {
 	if v == nil {
@@ -66,6 +154,11 @@ func DefaultResponseEncoder(w http.ResponseWriter, r *http.Request, v interface{
 		http.Redirect(w, r, url, code)
 		return nil
 	}
+	if hb, ok := v.(*httpbody.HttpBody); ok {
+		w.Header().Set("Content-Type", hb.ContentType)
+		_, err := w.Write(hb.Data)
+		return err
+	}
 	codec, _ := CodecForRequest(r, "Accept")
 	data, err := codec.Marshal(v)
 	if err != nil {
@@ -0,0 +1,296 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+)
+
+// CompressOption is compression filter option.
+type CompressOption func(*compress)
+
+// CompressMinLength with the minimum length of a response body to be compressed,
+// smaller responses are sent uncompressed. Default is 1024 bytes.
+func CompressMinLength(n int) CompressOption {
+	return func(c *compress) {
+		c.minLength = n
+	}
+}
+
+// CompressContentTypes with the content types of the responses to be compressed,
+// a wildcard subtype like "text/*" allows all the subtypes.
+// Default is text/*, application/json, application/proto, application/xml,
+// application/yaml and application/javascript.
+func CompressContentTypes(types ...string) CompressOption {
+	return func(c *compress) {
+		c.types = c.types[:0]
+		for _, t := range types {
+			c.types = append(c.types, strings.ToLower(t))
+		}
+	}
+}
+
+// CompressEncodings with the content-codings in order of server preference, only
+// the ones registered by encoding.RegisterCompressor are used.
+// Default is br, zstd, gzip and deflate.
+func CompressEncodings(names ...string) CompressOption {
+	return func(c *compress) {
+		c.encodings = c.encodings[:0]
+		for _, name := range names {
+			c.encodings = append(c.encodings, strings.ToLower(name))
+		}
+	}
+}
+
+type compress struct {
+	minLength int
+	types     []string
+	encodings []string
+}
+
+// Compress returns a FilterFunc that compresses the responses with the
+// content-coding negotiated by the Accept-Encoding header of the request.
+// A streaming response which is flushed before reaching the minimum length
+// is compressed as long as its content type is allowed.
+func Compress(opts ...CompressOption) FilterFunc {
+	c := &compress{
+		minLength: 1024,
+		types:     []string{"text/*", "application/json", "application/proto", "application/xml", "application/yaml", "application/javascript"},
+		encodings: []string{"br", "zstd", "gzip", "deflate"},
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			compressor := c.negotiate(req.Header.Get("Accept-Encoding"))
+			if compressor == nil || req.Method == http.MethodHead {
+				next.ServeHTTP(w, req)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: w, c: c, compressor: compressor}
+			defer cw.close()
+			next.ServeHTTP(cw, req)
+		})
+	}
+}
+
+// negotiate returns the compressor with the highest quality in the Accept-Encoding,
+// ties are broken by the server preference.
+func (c *compress) negotiate(accept string) encoding.Compressor {
+	if accept == "" {
+		return nil
+	}
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		name, q := parseCoding(part)
+		if name != "" {
+			qualities[name] = q
+		}
+	}
+	var (
+		best    encoding.Compressor
+		quality float64
+	)
+	for _, name := range c.encodings {
+		q, ok := qualities[name]
+		if !ok {
+			q = qualities["*"]
+		}
+		if q <= quality {
+			continue
+		}
+		if compressor := encoding.GetCompressor(name); compressor != nil {
+			best, quality = compressor, q
+		}
+	}
+	return best
+}
+
+func (c *compress) isTypeAllowed(contentType string) bool {
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	for _, t := range c.types {
+		if t == contentType {
+			return true
+		}
+		if strings.HasSuffix(t, "/*") && strings.HasPrefix(contentType, t[:len(t)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCoding(v string) (name string, q float64) {
+	q = 1
+	params := strings.Split(v, ";")
+	name = strings.ToLower(strings.TrimSpace(params[0]))
+	for _, param := range params[1:] {
+		param = strings.TrimSpace(param)
+		if strings.HasPrefix(param, "q=") {
+			if f, err := strconv.ParseFloat(param[2:], 64); err == nil {
+				q = f
+			}
+		}
+	}
+	return name, q
+}
+
+// compressWriter buffers the response body until the minimum length is reached,
+// then decides whether to compress it according to the response headers.
+type compressWriter struct {
+	http.ResponseWriter
+	c          *compress
+	compressor encoding.Compressor
+	code       int
+	buf        []byte
+	decided    bool
+	hijacked   bool
+	zw         io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code >= 100 && code < 200 {
+		// informational responses are sent as is.
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.code == 0 {
+		w.code = code
+	}
+	if !bodyAllowedForStatus(code) {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	if w.decided {
+		if w.zw != nil {
+			return w.zw.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= w.c.minLength {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// decide writes the header and the buffered body, which is compressed if
+// enough is true and the response is eligible for compression.
+func (w *compressWriter) decide(enough bool) error {
+	w.decided = true
+	header := w.Header()
+	if enough && w.code != http.StatusPartialContent && header.Get("Content-Encoding") == "" {
+		contentType := header.Get("Content-Type")
+		if contentType == "" && len(w.buf) > 0 {
+			contentType = http.DetectContentType(w.buf)
+		}
+		if w.c.isTypeAllowed(contentType) {
+			if zw, err := w.compressor.Compress(w.ResponseWriter); err == nil {
+				w.zw = zw
+				header.Set("Content-Encoding", w.compressor.Name())
+				header.Del("Content-Length")
+			}
+		}
+	}
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	var err error
+	if w.zw != nil {
+		_, err = w.zw.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush sends the buffered data to the client.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(true); err != nil {
+			return
+		}
+	}
+	if f, ok := w.zw.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return
+		}
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressWriter) close() {
+	if w.hijacked {
+		return
+	}
+	if !w.decided {
+		if w.code == 0 && len(w.buf) == 0 {
+			// nothing is written by the handler.
+			return
+		}
+		if err := w.decide(len(w.buf) >= w.c.minLength); err != nil {
+			return
+		}
+	}
+	if w.zw != nil {
+		_ = w.zw.Close()
+	}
+}
+
+func bodyAllowedForStatus(code int) bool {
+	switch {
+	case code >= 100 && code <= 199:
+		return false
+	case code == http.StatusNoContent:
+		return false
+	case code == http.StatusNotModified:
+		return false
+	}
+	return true
+}
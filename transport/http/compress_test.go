@@ -0,0 +1,201 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("kratos ", 512)
+	srv := NewServer(Filter(Compress(CompressMinLength(1024))))
+	r := srv.Route("/")
+	r.GET("/large", func(ctx Context) error {
+		return ctx.String(http.StatusOK, large)
+	})
+	r.GET("/small", func(ctx Context) error {
+		return ctx.String(http.StatusOK, "kratos")
+	})
+	r.GET("/image", func(ctx Context) error {
+		return ctx.Blob(http.StatusOK, "image/png", []byte(large))
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		accept   string
+		encoding string
+	}{
+		{name: "gzip", path: "/large", accept: "gzip, deflate", encoding: "gzip"},
+		{name: "quality", path: "/large", accept: "gzip;q=0.5, deflate", encoding: "deflate"},
+		{name: "wildcard", path: "/large", accept: "*", encoding: "gzip"},
+		{name: "rejected", path: "/large", accept: "gzip;q=0, identity", encoding: ""},
+		{name: "unsupported", path: "/large", accept: "compress", encoding: ""},
+		{name: "no accept", path: "/large", encoding: ""},
+		{name: "below threshold", path: "/small", accept: "gzip", encoding: ""},
+		{name: "content type", path: "/image", accept: "gzip", encoding: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			if test.accept != "" {
+				req.Header.Set("Accept-Encoding", test.accept)
+			}
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d", w.Code)
+			}
+			if got := w.Header().Get("Content-Encoding"); got != test.encoding {
+				t.Fatalf("expected Content-Encoding %q, got %q", test.encoding, got)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("expected Vary Accept-Encoding, got %q", got)
+			}
+			if test.encoding != "gzip" {
+				return
+			}
+			zr, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(zr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != large {
+				t.Errorf("unexpected body: %d bytes", len(data))
+			}
+		})
+	}
+}
+
+func TestCompressFlush(t *testing.T) {
+	srv := NewServer(Filter(Compress()))
+	srv.Route("/").GET("/events", func(ctx Context) error {
+		stream := SSE(ctx)
+		if err := stream.Send(&Event{Data: []byte("hello")}); err != nil {
+			return err
+		}
+		return stream.Close(nil)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if !w.Flushed {
+		t.Error("expected the response to be flushed")
+	}
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected gzip, got %q", got)
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(zr)
+	if string(data) != "data: hello\n\n" {
+		t.Errorf("unexpected body: %q", data)
+	}
+}
+
+func TestDecompressRequest(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write([]byte(`{"a":"1"}`))
+	_ = zw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(buf.Bytes()))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	v := struct {
+		A string `json:"a"`
+	}{}
+	if err := DefaultRequestDecoder(req, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A != "1" {
+		t.Errorf("expected 1, got %q", v.A)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "compress")
+	if err := DefaultRequestDecoder(req, &v); errors.Code(err) != http.StatusUnsupportedMediaType {
+		t.Errorf("expected 415, got %v", err)
+	}
+}
+
+type closeDecompressor struct {
+	closed *int32
+}
+
+type closeReader struct {
+	io.Reader
+	closed *int32
+}
+
+func (r closeReader) Close() error {
+	atomic.AddInt32(r.closed, 1)
+	return nil
+}
+
+func (c closeDecompressor) Compress(w io.Writer) (io.WriteCloser, error) { return nil, nil }
+func (c closeDecompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return closeReader{Reader: r, closed: c.closed}, nil
+}
+func (c closeDecompressor) Name() string { return "close-decompress-test" }
+
+func TestDecompressRequestClosed(t *testing.T) {
+	var closed int32
+	encoding.RegisterCompressor(closeDecompressor{closed: &closed})
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":"1"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "close-decompress-test, close-decompress-test")
+	var v map[string]string
+	if err := DefaultRequestDecoder(req, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v["a"] != "1" || atomic.LoadInt32(&closed) != 2 {
+		t.Errorf("expected the decompressors closed, got %v %d", v, closed)
+	}
+}
+
+func TestClientCompressor(t *testing.T) {
+	var encoding string
+	srv := NewServer()
+	srv.Route("/").POST("/echo", func(ctx Context) error {
+		encoding = ctx.Header().Get("Content-Encoding")
+		var in map[string]string
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		return ctx.Result(http.StatusOK, in)
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	client, err := NewClient(context.Background(), WithEndpoint(strings.TrimPrefix(ts.URL, "http://")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]string
+	if err = client.Invoke(context.Background(), http.MethodPost, "/echo", map[string]string{"a": "1"}, &out, UseCompressor("gzip")); err != nil {
+		t.Fatal(err)
+	}
+	if encoding != "gzip" || out["a"] != "1" {
+		t.Errorf("expected gzip request echoed, got %q %v", encoding, out)
+	}
+	if err = client.Invoke(context.Background(), http.MethodPost, "/echo", nil, &out, UseCompressor("unknown")); err == nil {
+		t.Error("expected an error for an unregistered compressor")
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"encoding/xml"
+	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"time"
@@ -42,20 +44,36 @@ type Context interface {
 }
 
 type responseWriter struct {
-	code int
-	w    http.ResponseWriter
+	code        int
+	wroteHeader bool
+	w           http.ResponseWriter
 }
 
 func (w *responseWriter) reset(res http.ResponseWriter) {
 	w.w = res
 	w.code = http.StatusOK
+	w.wroteHeader = false
 }
 func (w *responseWriter) Header() http.Header        { return w.w.Header() }
 func (w *responseWriter) WriteHeader(statusCode int) { w.code = statusCode }
 func (w *responseWriter) Write(data []byte) (int, error) {
-	w.w.WriteHeader(w.code)
+	w.writeHeader()
 	return w.w.Write(data)
 }
+func (w *responseWriter) Flush() {
+	if f, ok := w.w.(http.Flusher); ok {
+		w.writeHeader()
+		f.Flush()
+	}
+}
+
+// writeHeader writes the deferred status code once.
+func (w *responseWriter) writeHeader() {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.w.WriteHeader(w.code)
+	}
+}
 
 type wrapper struct {
 	router *Router
@@ -183,3 +201,27 @@ func (c *wrapper) Value(key interface{}) interface{} {
 	}
 	return c.req.Context().Value(key)
 }
+
+// File replies the content of ctx with the support of Range and conditional requests,
+// the ETag is generated from the modtime and size if it is not set.
+func File(ctx Context, name string, content io.ReadSeeker, modtime time.Time) error {
+	res := ctx.Response()
+	if res.Header().Get("Etag") == "" && !modtime.IsZero() {
+		size, err := content.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+		if _, err = content.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		res.Header().Set("Etag", fmt.Sprintf(`"%x-%x"`, modtime.UnixNano(), size))
+	}
+	http.ServeContent(res, ctx.Request(), name, modtime, content)
+	return nil
+}
+
+// Attachment replies the content of ctx as File, which is downloaded as the name by browsers.
+func Attachment(ctx Context, name string, content io.ReadSeeker, modtime time.Time) error {
+	ctx.Response().Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	return File(ctx, name, content, modtime)
+}
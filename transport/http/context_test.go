@@ -83,7 +83,7 @@ func TestContextResponse(t *testing.T) {
 		router: &Router{srv: &Server{enc: DefaultResponseEncoder}},
 		req:    &http.Request{Method: "POST"},
 		res:    res,
-		w:      responseWriter{200, res},
+		w:      responseWriter{code: 200, w: res},
 	}
 	if !reflect.DeepEqual(w.Response(), res) {
 		t.Errorf("expected %v, got %v", res, w.Response())
@@ -220,3 +220,32 @@ func TestContextCtx(t *testing.T) {
 		t.Errorf("expected %v, got %v", nil, v)
 	}
 }
+
+type headerCounter struct {
+	*httptest.ResponseRecorder
+	count int
+}
+
+func (w *headerCounter) WriteHeader(code int) {
+	w.count++
+	w.ResponseRecorder.WriteHeader(code)
+}
+
+func TestResponseWriterHeader(t *testing.T) {
+	res := &headerCounter{ResponseRecorder: httptest.NewRecorder()}
+	var w responseWriter
+	w.reset(res)
+	w.WriteHeader(http.StatusCreated)
+	w.Flush()
+	_, _ = w.Write([]byte("hello"))
+	w.Flush()
+	_, _ = w.Write([]byte(" world"))
+	if res.count != 1 || res.Code != http.StatusCreated {
+		t.Errorf("expected the status written once, got %d times of %d", res.count, res.Code)
+	}
+	w.reset(res)
+	_, _ = w.Write([]byte("again"))
+	if res.count != 2 {
+		t.Errorf("expected the status written after reset, got %d times", res.count)
+	}
+}
@@ -0,0 +1,300 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// CORSOption is CORS filter option.
+type CORSOption func(*cors)
+
+// CORSAllowedOrigins with the origins a cross-domain request can be executed from.
+// "*" allows all origins, and a wildcard subdomain like "https://*.example.com" allows
+// all the subdomains of example.com. Default is "*".
+func CORSAllowedOrigins(origins ...string) CORSOption {
+	return func(c *cors) {
+		c.origins = c.origins[:0]
+		for _, origin := range origins {
+			c.origins = append(c.origins, strings.ToLower(origin))
+		}
+	}
+}
+
+// CORSAllowOriginFunc with a custom function to validate the origin,
+// it takes precedence over the allowed origins.
+func CORSAllowOriginFunc(f func(origin string) bool) CORSOption {
+	return func(c *cors) {
+		c.allowOrigin = f
+	}
+}
+
+// CORSAllowedMethods with the methods allowed for cross-domain requests.
+// Default is GET, HEAD, POST, PUT, PATCH and DELETE.
+func CORSAllowedMethods(methods ...string) CORSOption {
+	return func(c *cors) {
+		c.methods = c.methods[:0]
+		for _, method := range methods {
+			c.methods = append(c.methods, strings.ToUpper(method))
+		}
+	}
+}
+
+// CORSAllowedHeaders with the non simple headers allowed for cross-domain requests,
+// "*" allows all the headers. Default is Accept, Authorization, Content-Type and X-Requested-With.
+func CORSAllowedHeaders(headers ...string) CORSOption {
+	return func(c *cors) {
+		c.headers = c.headers[:0]
+		for _, header := range headers {
+			c.headers = append(c.headers, http.CanonicalHeaderKey(header))
+		}
+	}
+}
+
+// CORSExposedHeaders with the headers which are safe to expose to the client.
+func CORSExposedHeaders(headers ...string) CORSOption {
+	return func(c *cors) {
+		c.exposed = headers
+	}
+}
+
+// CORSAllowCredentials with whether the request can include user credentials
+// like cookies, HTTP authentication or client side SSL certificates. It requires
+// the origins to be listed, the credentials are not allowed for the origins of "*",
+// which are replied as the literal "*" as the CORS spec requires.
+func CORSAllowCredentials(allow bool) CORSOption {
+	return func(c *cors) {
+		c.credentials = allow
+	}
+}
+
+// CORSMaxAge with how long the results of a preflight request can be cached.
+func CORSMaxAge(age time.Duration) CORSOption {
+	return func(c *cors) {
+		c.maxAge = age
+	}
+}
+
+type cors struct {
+	origins     []string
+	allowOrigin func(origin string) bool
+	methods     []string
+	headers     []string
+	exposed     []string
+	credentials bool
+	maxAge      time.Duration
+}
+
+// CORS returns a FilterFunc that handles Cross-Origin Resource Sharing.
+// It can be used as a server filter for all routes, or as a Router filter
+// for a group of routes, whose preflight requests are routed by the server
+// according to the Access-Control-Request-Method header.
+func CORS(opts ...CORSOption) FilterFunc {
+	c := &cors{
+		origins: []string{"*"},
+		methods: []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+		headers: []string{"Accept", "Authorization", "Content-Type", "X-Requested-With"},
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if isPreflight(req) {
+				markPreflightHandled(req.Context())
+				c.preflight(w, req)
+				return
+			}
+			c.actual(w, req)
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+func (c *cors) preflight(w http.ResponseWriter, req *http.Request) {
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	origin := req.Header.Get("Origin")
+	method := strings.ToUpper(req.Header.Get("Access-Control-Request-Method"))
+	headers := parseHeaderList(req.Header.Get("Access-Control-Request-Headers"))
+	if !c.isOriginAllowed(origin) || !c.isMethodAllowed(method) || !c.areHeadersAllowed(headers) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	c.setOrigin(header, origin)
+	header.Set("Access-Control-Allow-Methods", method)
+	if len(headers) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if c.allowCredentials() {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if c.maxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(c.maxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *cors) actual(w http.ResponseWriter, req *http.Request) {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return
+	}
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	if !c.isOriginAllowed(origin) || !c.isMethodAllowed(req.Method) {
+		return
+	}
+	c.setOrigin(header, origin)
+	if len(c.exposed) > 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(c.exposed, ", "))
+	}
+	if c.allowCredentials() {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *cors) setOrigin(header http.Header, origin string) {
+	if c.anyOrigin() {
+		header.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	header.Set("Access-Control-Allow-Origin", origin)
+}
+
+// anyOrigin reports whether all the origins are allowed by "*".
+func (c *cors) anyOrigin() bool {
+	if c.allowOrigin != nil {
+		return false
+	}
+	for _, o := range c.origins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// allowCredentials reports whether the credentials are allowed, which are not
+// with the wildcard origin, otherwise any site could send the credentialed requests.
+func (c *cors) allowCredentials() bool {
+	return c.credentials && !c.anyOrigin()
+}
+
+func (c *cors) isOriginAllowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if c.allowOrigin != nil {
+		return c.allowOrigin(origin)
+	}
+	origin = strings.ToLower(origin)
+	for _, o := range c.origins {
+		if o == "*" || o == origin {
+			return true
+		}
+		if i := strings.Index(o, "*"); i >= 0 {
+			prefix, suffix := o[:i], o[i+1:]
+			if len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *cors) isMethodAllowed(method string) bool {
+	if method == http.MethodOptions {
+		return true
+	}
+	for _, m := range c.methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cors) areHeadersAllowed(headers []string) bool {
+	for _, h := range headers {
+		allowed := false
+		for _, a := range c.headers {
+			if a == "*" || a == h {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+func parseHeaderList(v string) []string {
+	if v == "" {
+		return nil
+	}
+	parts := strings.Split(v, ",")
+	headers := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			headers = append(headers, http.CanonicalHeaderKey(p))
+		}
+	}
+	return headers
+}
+
+func isPreflight(req *http.Request) bool {
+	return req.Method == http.MethodOptions &&
+		req.Header.Get("Origin") != "" &&
+		req.Header.Get("Access-Control-Request-Method") != ""
+}
+
+type preflightKey struct{}
+
+// markPreflightHandled marks the preflight request routed by the server as handled.
+func markPreflightHandled(ctx context.Context) {
+	if handled, ok := ctx.Value(preflightKey{}).(*bool); ok {
+		*handled = true
+	}
+}
+
+// methodNotAllowed routes a preflight request to the route matching the
+// Access-Control-Request-Method, so that the CORS filters of the route can
+// answer it, otherwise the request is served by the next handler.
+func (s *Server) methodNotAllowed(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !isPreflight(req) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		handled := false
+		preq := req.Clone(context.WithValue(req.Context(), preflightKey{}, &handled))
+		preq.Method = strings.ToUpper(req.Header.Get("Access-Control-Request-Method"))
+		var match mux.RouteMatch
+		if !s.router.Match(preq, &match) || match.Route == nil || !s.isRouterRoute(match.Route) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		preq.Method = req.Method
+		cw := &captureWriter{ResponseWriter: w}
+		match.Handler.ServeHTTP(cw, preq)
+		if !handled && cw.code == 0 {
+			next.ServeHTTP(w, req)
+		}
+	})
+}
+
+func (s *Server) isRouterRoute(route *mux.Route) bool {
+	s.routesMu.RLock()
+	defer s.routesMu.RUnlock()
+	_, ok := s.routes[route]
+	return ok
+}
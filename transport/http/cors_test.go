@@ -0,0 +1,141 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	srv := NewServer()
+	api := srv.Route("/api", CORS(
+		CORSAllowedOrigins("https://*.example.com"),
+		CORSAllowedHeaders("Content-Type", "X-Token"),
+		CORSExposedHeaders("X-Request-Id"),
+		CORSAllowCredentials(true),
+		CORSMaxAge(time.Hour),
+	))
+	api.GET("/users/{id}", func(ctx Context) error {
+		return ctx.String(http.StatusOK, "user")
+	})
+	srv.Route("/internal").GET("/users/{id}", func(ctx Context) error {
+		return ctx.String(http.StatusOK, "user")
+	})
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		header  map[string]string
+		code    int
+		origin  string
+		methods string
+	}{
+		{
+			name:    "preflight",
+			method:  http.MethodOptions,
+			path:    "/api/users/1",
+			header:  map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "GET", "Access-Control-Request-Headers": "x-token"},
+			code:    http.StatusNoContent,
+			origin:  "https://app.example.com",
+			methods: "GET",
+		},
+		{
+			name:   "preflight disallowed origin",
+			method: http.MethodOptions,
+			path:   "/api/users/1",
+			header: map[string]string{"Origin": "https://example.org", "Access-Control-Request-Method": "GET"},
+			code:   http.StatusNoContent,
+		},
+		{
+			name:   "preflight disallowed header",
+			method: http.MethodOptions,
+			path:   "/api/users/1",
+			header: map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "GET", "Access-Control-Request-Headers": "x-other"},
+			code:   http.StatusNoContent,
+		},
+		{
+			name:   "preflight without cors filter",
+			method: http.MethodOptions,
+			path:   "/internal/users/1",
+			header: map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "GET"},
+			code:   http.StatusNotFound,
+		},
+		{
+			name:   "actual",
+			method: http.MethodGet,
+			path:   "/api/users/1",
+			header: map[string]string{"Origin": "https://app.example.com"},
+			code:   http.StatusOK,
+			origin: "https://app.example.com",
+		},
+		{
+			name:   "actual without cors filter",
+			method: http.MethodGet,
+			path:   "/internal/users/1",
+			header: map[string]string{"Origin": "https://app.example.com"},
+			code:   http.StatusOK,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.path, nil)
+			for k, v := range test.header {
+				req.Header.Set(k, v)
+			}
+			res := httptest.NewRecorder()
+			srv.ServeHTTP(res, req)
+			if res.Code != test.code {
+				t.Errorf("expected code %d got %d", test.code, res.Code)
+			}
+			if got := res.Header().Get("Access-Control-Allow-Origin"); got != test.origin {
+				t.Errorf("expected origin %q got %q", test.origin, got)
+			}
+			if got := res.Header().Get("Access-Control-Allow-Methods"); got != test.methods {
+				t.Errorf("expected methods %q got %q", test.methods, got)
+			}
+			if test.origin != "" && res.Header().Get("Access-Control-Allow-Credentials") != "true" {
+				t.Errorf("expected credentials allowed")
+			}
+		})
+	}
+}
+
+func TestCORSServerFilter(t *testing.T) {
+	srv := NewServer(Filter(CORS()))
+	srv.HandleFunc("/raw", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("raw"))
+	})
+	req := httptest.NewRequest(http.MethodOptions, "/raw", nil)
+	req.Header.Set("Origin", "https://kratos.dev")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	res := httptest.NewRecorder()
+	srv.ServeHTTP(res, req)
+	if res.Code != http.StatusNoContent || res.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("unexpected preflight response %d %v", res.Code, res.Header())
+	}
+	if res.Header().Get("Access-Control-Max-Age") != "" {
+		t.Errorf("unexpected max age")
+	}
+}
+
+func TestCORSWildcardCredentials(t *testing.T) {
+	srv := NewServer(Filter(CORS(CORSAllowedOrigins("*"), CORSAllowCredentials(true))))
+	srv.HandleFunc("/raw", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("raw"))
+	})
+	for _, method := range []string{http.MethodOptions, http.MethodGet} {
+		req := httptest.NewRequest(method, "/raw", nil)
+		req.Header.Set("Origin", "https://evil.example")
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		res := httptest.NewRecorder()
+		srv.ServeHTTP(res, req)
+		if got := res.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+			t.Errorf("%s: expected the wildcard origin, got %q", method, got)
+		}
+		if got := res.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+			t.Errorf("%s: expected no credentials with the wildcard origin, got %q", method, got)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"html"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// Reasons of the errors decoded from the gateway error responses without
+// a kratos error body, e.g. returned by the proxies, which can be retried.
+const (
+	ReasonBadGateway         = "BAD_GATEWAY"
+	ReasonServiceUnavailable = "SERVICE_UNAVAILABLE"
+	ReasonGatewayTimeout     = "GATEWAY_TIMEOUT"
+)
+
+// maxErrorBodySize is the maximum size of the error body to be decoded.
+const maxErrorBodySize = 64 << 10
+
+// maxErrorMessageSize is the maximum size of the message of the text error pages.
+const maxErrorMessageSize = 256
+
+// defaultErrorHeaders are the response headers added to the metadata of the errors
+// by DefaultErrorDecoder, the others are not as they may contain the credentials,
+// e.g. Set-Cookie or WWW-Authenticate.
+var defaultErrorHeaders = []string{"Retry-After", "X-Request-Id"}
+
+var (
+	titleRegexp = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	tagRegexp   = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// IsRetryable reports whether the error is a gateway error which can be retried.
+func IsRetryable(err error) bool {
+	se := errors.FromError(err)
+	if se == nil {
+		return false
+	}
+	return se.Reason != "" && se.Reason == gatewayReason(int(se.Code))
+}
+
+func gatewayReason(code int) string {
+	switch code {
+	case http.StatusBadGateway:
+		return ReasonBadGateway
+	case http.StatusServiceUnavailable:
+		return ReasonServiceUnavailable
+	case http.StatusGatewayTimeout:
+		return ReasonGatewayTimeout
+	}
+	return errors.UnknownReason
+}
+
+// decodeErrorBody decodes the kratos error from the body, nil if the body is not one,
+// e.g. an HTML page of the proxies.
+func decodeErrorBody(res *http.Response, data []byte) *errors.Error {
+	if len(data) == 0 {
+		return nil
+	}
+	if mediaType, _, err := mime.ParseMediaType(res.Header.Get("Content-Type")); err == nil && strings.HasPrefix(mediaType, "text/") {
+		return nil
+	}
+	e := new(errors.Error)
+	if err := CodecForResponse(res).Unmarshal(data, e); err != nil || (e.Reason == "" && e.Message == "") {
+		return nil
+	}
+	return e
+}
+
+// errorMessage returns the message of the text error page, which is the title
+// of the HTML page or the text without tags.
+func errorMessage(code int, data []byte) string {
+	text := string(data)
+	if m := titleRegexp.FindStringSubmatch(text); m != nil {
+		text = m[1]
+	} else {
+		text = tagRegexp.ReplaceAllString(text, " ")
+	}
+	text = strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+	if len(text) > maxErrorMessageSize {
+		text = strings.ToValidUTF8(text[:maxErrorMessageSize], "") + "..."
+	}
+	if text == "" {
+		return http.StatusText(code)
+	}
+	return text
+}
+
+// headerMetadata adds the allowed response headers to the metadata of the error,
+// the metadata decoded from the body takes precedence.
+func headerMetadata(md map[string]string, header http.Header, keys []string) map[string]string {
+	for _, k := range keys {
+		k = http.CanonicalHeaderKey(k)
+		v := header.Values(k)
+		if len(v) == 0 {
+			continue
+		}
+		if md == nil {
+			md = make(map[string]string, len(keys))
+		}
+		if _, ok := md[k]; !ok {
+			md[k] = strings.Join(v, ", ")
+		}
+	}
+	return md
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+func TestErrorDecoder(t *testing.T) {
+	tests := []struct {
+		name        string
+		code        int
+		contentType string
+		body        string
+		reason      string
+		message     string
+		retryable   bool
+	}{
+		{"kratos", 404, "application/json", `{"reason":"USER_NOT_FOUND","message":"user not found","metadata":{"id":"1"}}`, "USER_NOT_FOUND", "user not found", false},
+		{"html", 502, "text/html; charset=utf-8", "<html><head><title>502 Bad Gateway</title></head><body><h1>502 Bad Gateway</h1></body></html>", ReasonBadGateway, "502 Bad Gateway", true},
+		{"html without title", 504, "text/html", "<html><body><h1>Gateway &amp; Timeout</h1>\n<hr>nginx</body></html>", ReasonGatewayTimeout, "Gateway & Timeout nginx", true},
+		{"plain", 503, "text/plain", "upstream connect error or disconnect/reset before headers", ReasonServiceUnavailable, "upstream connect error or disconnect/reset before headers", true},
+		{"json of the proxies", 503, "application/json", `{"error":"no healthy upstream"}`, ReasonServiceUnavailable, `{"error":"no healthy upstream"}`, true},
+		{"empty", 500, "", "", errors.UnknownReason, "Internal Server Error", false},
+		{"kratos gateway error", 503, "application/json", `{"reason":"MAINTENANCE","message":"down for maintenance"}`, "MAINTENANCE", "down for maintenance", false},
+		{"long", 400, "text/plain", strings.Repeat("x", 300), errors.UnknownReason, strings.Repeat("x", maxErrorMessageSize) + "...", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := &http.Response{
+				StatusCode: test.code,
+				Header:     http.Header{"Content-Type": {test.contentType}, "X-Request-Id": {"abc"}, "Set-Cookie": {"session=secret"}},
+				Body:       io.NopCloser(bytes.NewBufferString(test.body)),
+			}
+			err := DefaultErrorDecoder(context.Background(), res)
+			se := errors.FromError(err)
+			if se == nil || int(se.Code) != test.code || se.Reason != test.reason || se.Message != test.message {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if IsRetryable(err) != test.retryable {
+				t.Errorf("expected retryable %v", test.retryable)
+			}
+			if se.Metadata["X-Request-Id"] != "abc" {
+				t.Errorf("expected the headers in the metadata, got %v", se.Metadata)
+			}
+			if _, ok := se.Metadata["Set-Cookie"]; ok {
+				t.Errorf("expected the headers not allowed to be dropped, got %v", se.Metadata)
+			}
+		})
+	}
+
+	// the metadata of the body takes precedence over the headers.
+	res := &http.Response{
+		StatusCode: 429,
+		Header:     http.Header{"Retry-After": {"10"}},
+		Body:       io.NopCloser(bytes.NewBufferString(`{"reason":"RATELIMIT","message":"slow down","metadata":{"Retry-After":"5"}}`)),
+	}
+	if se := errors.FromError(DefaultErrorDecoder(context.Background(), res)); se.Metadata["Retry-After"] != "5" {
+		t.Errorf("unexpected metadata: %v", se.Metadata)
+	}
+	// the headers of the metadata are configurable.
+	res = &http.Response{
+		StatusCode: 500,
+		Header:     http.Header{"X-Request-Id": {"abc"}, "X-Trace-Id": {"def"}},
+		Body:       io.NopCloser(bytes.NewBufferString("")),
+	}
+	if se := errors.FromError(NewErrorDecoder("x-trace-id")(context.Background(), res)); se.Metadata["X-Trace-Id"] != "def" || len(se.Metadata) != 1 {
+		t.Errorf("unexpected metadata: %v", se.Metadata)
+	}
+	if IsRetryable(nil) || IsRetryable(errors.New(502, "UPSTREAM", "")) {
+		t.Error("expected not retryable")
+	}
+}
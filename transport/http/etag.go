@@ -0,0 +1,136 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// ETag with the strong ETags of the GET and HEAD responses encoded by the ResponseEncoder,
+// which are computed from the encoded bodies unless set by SetETag, and the requests
+// with a matched If-None-Match are answered with 304 Not Modified.
+func ETag() ServerOption {
+	return func(s *Server) {
+		s.etag = true
+	}
+}
+
+// SetETag sets the ETag of the response to the version of the resource,
+// which takes precedence over the one computed from the body.
+func SetETag(ctx context.Context, version string) {
+	if tr, ok := transport.FromServerContext(ctx); ok {
+		tr.ReplyHeader().Set("ETag", quoteETag(version))
+	}
+}
+
+// CheckETag checks the If-Match and If-None-Match preconditions of a mutating request
+// against the current version of the resource, an empty version means the resource
+// doesn't exist, and returns a 412 Precondition Failed error if they fail.
+// e.g. PUT with If-Match: "v1" fails when the resource has been changed to "v2".
+func CheckETag(ctx context.Context, version string) error {
+	tr, ok := transport.FromServerContext(ctx)
+	if !ok {
+		return nil
+	}
+	ht, ok := tr.(*Transport)
+	if !ok || ht.request == nil {
+		return nil
+	}
+	etag := ""
+	if version != "" {
+		etag = quoteETag(version)
+	}
+	if v := ht.request.Header.Get("If-Match"); v != "" && !matchETag(v, etag, false) {
+		return errors.New(http.StatusPreconditionFailed, "PRECONDITION_FAILED", "If-Match precondition failed")
+	}
+	if v := ht.request.Header.Get("If-None-Match"); v != "" && matchETag(v, etag, true) {
+		return errors.New(http.StatusPreconditionFailed, "PRECONDITION_FAILED", "If-None-Match precondition failed")
+	}
+	return nil
+}
+
+func quoteETag(version string) string {
+	if strings.HasPrefix(version, `"`) || strings.HasPrefix(version, `W/"`) {
+		return version
+	}
+	return `"` + version + `"`
+}
+
+// matchETag reports whether the etag matches the list of the header, the weak
+// comparison ignores the W/ prefix, and "*" matches any existing resource.
+func matchETag(list, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+	for _, v := range strings.Split(list, ",") {
+		v = strings.TrimSpace(v)
+		switch {
+		case v == "*":
+			return true
+		case weak:
+			if strings.TrimPrefix(v, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		case !strings.HasPrefix(v, "W/") && v == etag:
+			return true
+		}
+	}
+	return false
+}
+
+type bufferedWriter struct {
+	http.ResponseWriter
+	code int
+	buf  bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int)        { w.code = code }
+func (w *bufferedWriter) Write(b []byte) (int, error) { return w.buf.Write(b) }
+
+// etagEncoder buffers the encoded body of the GET and HEAD requests to compute the ETag.
+func etagEncoder(enc EncodeResponseFunc) EncodeResponseFunc {
+	return func(w http.ResponseWriter, r *http.Request, v interface{}) error {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			return enc(w, r, v)
+		}
+		bw := &bufferedWriter{ResponseWriter: w}
+		if err := enc(bw, r, v); err != nil {
+			return err
+		}
+		code := bw.code
+		if code == 0 {
+			code = http.StatusOK
+			if rw, ok := w.(*responseWriter); ok {
+				code = rw.code
+			}
+		}
+		if code == http.StatusOK {
+			etag := w.Header().Get("ETag")
+			if etag == "" {
+				sum := sha256.Sum256(bw.buf.Bytes())
+				etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+				w.Header().Set("ETag", etag)
+			}
+			if matchETag(r.Header.Get("If-None-Match"), etag, true) {
+				w.Header().Del("Content-Type")
+				w.WriteHeader(http.StatusNotModified)
+				// the status code is deferred by the writer of Context until the body is written.
+				if rw, ok := w.(*responseWriter); ok {
+					rw.writeHeader()
+				}
+				return nil
+			}
+		}
+		if bw.code != 0 {
+			w.WriteHeader(bw.code)
+		}
+		_, err := w.Write(bw.buf.Bytes())
+		return err
+	}
+}
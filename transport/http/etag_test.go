@@ -0,0 +1,103 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+func TestETag(t *testing.T) {
+	version := "v1"
+	srv := NewServer(ETag())
+	r := srv.Route("/")
+	r.GET("/users/{id}", func(ctx Context) error {
+		return ctx.Result(http.StatusOK, map[string]string{"id": ctx.Vars().Get("id")})
+	})
+	r.GET("/versions/{id}", func(ctx Context) error {
+		SetETag(ctx, version)
+		return ctx.Result(http.StatusOK, map[string]string{"id": ctx.Vars().Get("id"), "version": version})
+	})
+	r.PUT("/versions/{id}", func(ctx Context) error {
+		if err := CheckETag(ctx, version); err != nil {
+			return err
+		}
+		version = "v2"
+		return ctx.Result(http.StatusOK, nil)
+	})
+	r.POST("/versions", func(ctx Context) error {
+		return ctx.Result(http.StatusCreated, map[string]string{"id": "1"})
+	})
+
+	serve := func(method, path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(http.MethodGet, "/users/1")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || len(etag) != 34 || w.Body.String() != `{"id":"1"}` {
+		t.Fatalf("unexpected response: %d %s %s", w.Code, etag, w.Body.String())
+	}
+	if w = serve(http.MethodGet, "/users/1", "If-None-Match", etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("expected 304, got %d %s", w.Code, w.Body.String())
+	}
+	if w = serve(http.MethodGet, "/users/2", "If-None-Match", etag); w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("expected a different ETag, got %d %s", w.Code, w.Header().Get("ETag"))
+	}
+
+	// the version set by the handler takes precedence.
+	if w = serve(http.MethodGet, "/versions/1"); w.Header().Get("ETag") != `"v1"` {
+		t.Errorf("expected the handler-provided ETag, got %s", w.Header().Get("ETag"))
+	}
+	if w = serve(http.MethodGet, "/versions/1", "If-None-Match", `W/"v1", "v0"`); w.Code != http.StatusNotModified {
+		t.Errorf("expected 304 of the weak comparison, got %d", w.Code)
+	}
+	tests := []struct {
+		header []string
+		code   int
+	}{
+		{[]string{"If-Match", `"v0"`}, http.StatusPreconditionFailed},
+		{[]string{"If-Match", `W/"v1"`}, http.StatusPreconditionFailed},
+		{[]string{"If-None-Match", "*"}, http.StatusPreconditionFailed},
+		{[]string{"If-Match", `"v0", "v1"`}, http.StatusOK},
+		{[]string{"If-Match", `"v1"`}, http.StatusPreconditionFailed},
+		{nil, http.StatusOK},
+	}
+	for _, test := range tests {
+		w = serve(http.MethodPut, "/versions/1", test.header...)
+		if w.Code != test.code {
+			t.Errorf("%v: expected %d, got %d %s", test.header, test.code, w.Code, w.Body.String())
+		}
+		if test.code == http.StatusPreconditionFailed && w.Header().Get("ETag") != "" {
+			t.Errorf("unexpected ETag of the mutating request: %s", w.Header().Get("ETag"))
+		}
+	}
+
+	// the status code of the handler is kept and no ETag is computed.
+	if w = serve(http.MethodPost, "/versions"); w.Code != http.StatusCreated || w.Header().Get("ETag") != "" {
+		t.Errorf("unexpected response: %d %s", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestCheckETag(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/", nil)
+	req.Header.Set("If-Match", "*")
+	ctx := transport.NewServerContext(req.Context(), &Transport{request: req})
+	if err := CheckETag(ctx, ""); errors.Code(err) != http.StatusPreconditionFailed {
+		t.Errorf("expected 412 of the missing resource, got %v", err)
+	}
+	if err := CheckETag(ctx, "v1"); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+	if err := CheckETag(req.Context(), "v1"); err != nil {
+		t.Errorf("expected nil without transport, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+//go:generate protoc -I ../../third_party --go_out=../.. --go_opt=module=github.com/go-kratos/kratos/v2 kratos/http.proto
+
+// MaxBodySize with the maximum request body size of the server,
+// zero or negative means no limit.
+func MaxBodySize(n int64) ServerOption {
+	return func(s *Server) {
+		s.maxBodySize = n
+	}
+}
+
+// ContentTypeMaxBodySize with the maximum request body size for the media type,
+// e.g. "multipart/form-data" or "image/*", which takes precedence over MaxBodySize.
+func ContentTypeMaxBodySize(contentType string, n int64) ServerOption {
+	return func(s *Server) {
+		if s.contentTypeMaxBodySize == nil {
+			s.contentTypeMaxBodySize = make(map[string]int64)
+		}
+		s.contentTypeMaxBodySize[strings.ToLower(contentType)] = n
+	}
+}
+
+// routeConfig is the config of a route registered by Router.
+type routeConfig struct {
+	timeout     time.Duration
+	maxBodySize int64
+}
+
+func (s *Server) routeConfig(req *http.Request) routeConfig {
+	route := mux.CurrentRoute(req)
+	if route == nil {
+		return routeConfig{}
+	}
+	s.routesMu.RLock()
+	defer s.routesMu.RUnlock()
+	return s.routes[route]
+}
+
+// bodyLimit returns the maximum request body size, the route limit
+// takes precedence over the content type limit and the server limit.
+func (s *Server) bodyLimit(req *http.Request, rc routeConfig) int64 {
+	if rc.maxBodySize != 0 {
+		return rc.maxBodySize
+	}
+	if len(s.contentTypeMaxBodySize) > 0 {
+		if mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type")); err == nil {
+			if n, ok := s.contentTypeMaxBodySize[mediaType]; ok {
+				return n
+			}
+			if i := strings.IndexByte(mediaType, '/'); i > 0 {
+				if n, ok := s.contentTypeMaxBodySize[mediaType[:i]+"/*"]; ok {
+					return n
+				}
+			}
+		}
+	}
+	return s.maxBodySize
+}
+
+// limitBody limits the request body, and rejects the request whose
+// Content-Length exceeds the limit.
+func (s *Server) limitBody(w http.ResponseWriter, req *http.Request, rc routeConfig) bool {
+	n := s.bodyLimit(req, rc)
+	if n <= 0 || req.Body == nil || req.Body == http.NoBody {
+		return true
+	}
+	if req.ContentLength > n {
+		s.ene(w, req, errBodyTooLarge(n))
+		return false
+	}
+	req.Body = &maxBytesReader{r: req.Body, n: n, limit: n}
+	return true
+}
+
+func errBodyTooLarge(n int64) *errors.Error {
+	return errors.New(http.StatusRequestEntityTooLarge, "BODY_TOO_LARGE", fmt.Sprintf("request body exceeds %d bytes", n))
+}
+
+// maxBytesReader is like http.MaxBytesReader, but returns an errors.Error
+// with 413 status when the limit is exceeded.
+type maxBytesReader struct {
+	r     io.Reader
+	n     int64
+	limit int64
+	err   error
+}
+
+func (l *maxBytesReader) Read(p []byte) (n int, err error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// read one extra byte to detect whether the body exceeds the limit.
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err = l.r.Read(p)
+	if int64(n) <= l.n {
+		l.n -= int64(n)
+		l.err = err
+		return n, err
+	}
+	n = int(l.n)
+	l.n = 0
+	l.err = errBodyTooLarge(l.limit)
+	return n, l.err
+}
+
+func (l *maxBytesReader) Close() error {
+	if c, ok := l.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+func newLimitServer(opts ...ServerOption) *Server {
+	srv := NewServer(opts...)
+	bind := func(ctx Context) error {
+		var in map[string]interface{}
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		return ctx.Result(200, in)
+	}
+	r := srv.Route("/")
+	r.POST("/bind", bind)
+	r.MaxBodySize(64).POST("/small", bind)
+	r.MaxBodySize(-1).POST("/unlimited", bind)
+	return srv
+}
+
+func postJSON(srv *Server, path string, body []byte, chunked bool, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	if chunked {
+		req.ContentLength = -1
+	}
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	return w
+}
+
+func TestMaxBodySize(t *testing.T) {
+	srv := newLimitServer(MaxBodySize(128), ContentTypeMaxBodySize("text/*", 16))
+	small := []byte(`{"name":"kratos"}`)
+	large := []byte(`{"name":"` + strings.Repeat("k", 256) + `"}`)
+
+	tests := []struct {
+		path    string
+		body    []byte
+		chunked bool
+		code    int
+	}{
+		{"/bind", small, false, http.StatusOK},
+		{"/bind", large, false, http.StatusRequestEntityTooLarge},
+		{"/bind", large, true, http.StatusRequestEntityTooLarge},
+		{"/small", small, false, http.StatusOK},
+		{"/small", large[:100], true, http.StatusRequestEntityTooLarge},
+		{"/unlimited", large, false, http.StatusOK},
+	}
+	for _, test := range tests {
+		w := postJSON(srv, test.path, test.body, test.chunked)
+		if w.Code != test.code {
+			t.Errorf("%s: expected %d, got %d: %s", test.path, test.code, w.Code, w.Body.String())
+		}
+		if test.code == http.StatusRequestEntityTooLarge && !strings.Contains(w.Body.String(), "BODY_TOO_LARGE") {
+			t.Errorf("%s: unexpected body: %s", test.path, w.Body.String())
+		}
+	}
+
+	// the content type limit takes precedence over the server limit.
+	w := postJSON(srv, "/bind", small, false, "Content-Type", "text/plain")
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d", w.Code)
+	}
+}
+
+func TestMaxBodySizeDecompressed(t *testing.T) {
+	srv := newLimitServer(MaxBodySize(128))
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write([]byte(`{"name":"` + strings.Repeat("k", 1024) + `"}`))
+	_ = zw.Close()
+	w := postJSON(srv, "/bind", buf.Bytes(), false, "Content-Encoding", "gzip")
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestMaxBytesReader(t *testing.T) {
+	r := &maxBytesReader{r: strings.NewReader("hello"), n: 5, limit: 5}
+	if data, err := io.ReadAll(r); err != nil || string(data) != "hello" {
+		t.Errorf("unexpected result: %q %v", data, err)
+	}
+	r = &maxBytesReader{r: strings.NewReader("hello kratos"), n: 5, limit: 5}
+	data, err := io.ReadAll(r)
+	if string(data) != "hello" {
+		t.Errorf("unexpected data: %q", data)
+	}
+	if se := errors.FromError(err); se.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRouteTimeout(t *testing.T) {
+	srv := NewServer(Timeout(time.Second))
+	deadline := func(ctx Context) error {
+		d, ok := ctx.Deadline()
+		return ctx.JSON(200, map[string]interface{}{"ok": ok, "remain": time.Until(d).Round(time.Second).String()})
+	}
+	r := srv.Route("/")
+	r.GET("/default", deadline)
+	r.Timeout(10*time.Second).GET("/slow", deadline)
+	r.Group("/v1").Timeout(-1).GET("/stream", deadline)
+
+	tests := map[string]string{
+		"/default":   `{"ok":true,"remain":"1s"}`,
+		"/slow":      `{"ok":true,"remain":"10s"}`,
+		"/v1/stream": `{"ok":false,`,
+	}
+	for path, want := range tests {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if !strings.HasPrefix(w.Body.String(), want) {
+			t.Errorf("%s: expected %s, got %s", path, want, w.Body.String())
+		}
+	}
+}
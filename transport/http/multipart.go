@@ -0,0 +1,295 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+const sniffLen = 512
+
+// MultipartOption is a multipart reader option.
+type MultipartOption func(*multipartOptions)
+
+type multipartOptions struct {
+	maxFileSize   int64
+	maxFiles      int
+	maxValuesSize int64
+	memory        int64
+	tempDir       string
+	contentTypes  []string
+}
+
+// MaxFileSize with the maximum size of each file, zero means no limit.
+func MaxFileSize(n int64) MultipartOption {
+	return func(o *multipartOptions) {
+		o.maxFileSize = n
+	}
+}
+
+// MaxFiles with the maximum number of files, zero means no limit.
+func MaxFiles(n int) MultipartOption {
+	return func(o *multipartOptions) {
+		o.maxFiles = n
+	}
+}
+
+// MaxValuesSize with the maximum total size of the non-file values, default 10MB.
+func MaxValuesSize(n int64) MultipartOption {
+	return func(o *multipartOptions) {
+		o.maxValuesSize = n
+	}
+}
+
+// SpoolMemory with the maximum size of a spooled file kept in memory,
+// the larger ones are spilled to a temporary file, default 32MB.
+func SpoolMemory(n int64) MultipartOption {
+	return func(o *multipartOptions) {
+		o.memory = n
+	}
+}
+
+// SpoolDir with the directory of the temporary files, default os.TempDir.
+func SpoolDir(dir string) MultipartOption {
+	return func(o *multipartOptions) {
+		o.tempDir = dir
+	}
+}
+
+// AllowContentTypes with the allowed media types of the files, e.g. "image/*",
+// which are matched against the content type sniffed from the file content.
+func AllowContentTypes(contentTypes ...string) MultipartOption {
+	return func(o *multipartOptions) {
+		o.contentTypes = contentTypes
+	}
+}
+
+// MultipartReader is a streaming reader of a multipart/form-data request.
+type MultipartReader struct {
+	r         *multipart.Reader
+	opts      multipartOptions
+	values    url.Values
+	valueSize int64
+	files     int
+	part      *multipart.Part
+}
+
+// Multipart returns the streaming reader of the multipart/form-data request body of ctx.
+func Multipart(ctx Context, opts ...MultipartOption) (*MultipartReader, error) {
+	return newMultipartReader(ctx.Request(), opts...)
+}
+
+func newMultipartReader(req *http.Request, opts ...MultipartOption) (*MultipartReader, error) {
+	o := multipartOptions{
+		maxValuesSize: 10 << 20,
+		memory:        32 << 20,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	r, err := req.MultipartReader()
+	if err != nil {
+		return nil, errors.BadRequest("MULTIPART", err.Error())
+	}
+	return &MultipartReader{r: r, opts: o, values: make(url.Values)}, nil
+}
+
+// Values returns the non-file values which have been read, the values
+// after a file are available once the file has been iterated over.
+func (r *MultipartReader) Values() url.Values {
+	return r.values
+}
+
+// NextFile returns the next file of the form, the previous file is discarded
+// if it has not been read. It returns io.EOF when there are no more files.
+func (r *MultipartReader) NextFile() (*FormFile, error) {
+	for {
+		if r.part != nil {
+			r.part.Close()
+			r.part = nil
+		}
+		p, err := r.r.NextPart()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, multipartError(err)
+		}
+		r.part = p
+		name := p.FormName()
+		if name == "" {
+			continue
+		}
+		if p.FileName() == "" {
+			if err := r.readValue(name, p); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		r.files++
+		if r.opts.maxFiles > 0 && r.files > r.opts.maxFiles {
+			return nil, errors.New(http.StatusRequestEntityTooLarge, "MULTIPART", fmt.Sprintf("multipart files exceed %d", r.opts.maxFiles))
+		}
+		return newFormFile(p, r.opts)
+	}
+}
+
+func (r *MultipartReader) readValue(name string, p *multipart.Part) error {
+	remain := r.opts.maxValuesSize - r.valueSize
+	data, err := io.ReadAll(io.LimitReader(p, remain+1))
+	if err != nil {
+		return multipartError(err)
+	}
+	if int64(len(data)) > remain {
+		return errors.New(http.StatusRequestEntityTooLarge, "MULTIPART", fmt.Sprintf("multipart values exceed %d bytes", r.opts.maxValuesSize))
+	}
+	r.valueSize += int64(len(data))
+	r.values.Add(name, string(data))
+	return nil
+}
+
+func multipartError(err error) error {
+	if se := new(errors.Error); errors.As(err, &se) {
+		return se
+	}
+	return errors.BadRequest("MULTIPART", err.Error())
+}
+
+// FormFile is a file of a multipart form, which is read as a stream.
+type FormFile struct {
+	// FieldName is the name of the form field.
+	FieldName string
+	// Filename is the base name of the file.
+	Filename string
+	// ContentType is the declared content type, or the sniffed one if
+	// the declared is absent or application/octet-stream.
+	ContentType string
+	// DetectedContentType is the content type sniffed from the content.
+	DetectedContentType string
+	// Header is the MIME header of the part.
+	Header textproto.MIMEHeader
+
+	r    *bufio.Reader
+	size int64
+	opts multipartOptions
+}
+
+func newFormFile(p *multipart.Part, opts multipartOptions) (*FormFile, error) {
+	f := &FormFile{
+		FieldName:   p.FormName(),
+		Filename:    p.FileName(),
+		ContentType: p.Header.Get("Content-Type"),
+		Header:      p.Header,
+		r:           bufio.NewReaderSize(p, sniffLen),
+		opts:        opts,
+	}
+	head, err := f.r.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return nil, multipartError(err)
+	}
+	f.DetectedContentType = http.DetectContentType(head)
+	if f.ContentType == "" || f.ContentType == "application/octet-stream" {
+		f.ContentType = f.DetectedContentType
+	}
+	if len(opts.contentTypes) > 0 && !matchContentType(f.DetectedContentType, opts.contentTypes) {
+		return nil, errors.New(http.StatusUnsupportedMediaType, "MULTIPART", fmt.Sprintf("unsupported file content type: %s", f.DetectedContentType))
+	}
+	return f, nil
+}
+
+// Read reads the content of the file.
+func (f *FormFile) Read(p []byte) (n int, err error) {
+	if f.opts.maxFileSize > 0 {
+		if remain := f.opts.maxFileSize - f.size + 1; int64(len(p)) > remain {
+			p = p[:remain]
+		}
+	}
+	n, err = f.r.Read(p)
+	f.size += int64(n)
+	if f.opts.maxFileSize > 0 && f.size > f.opts.maxFileSize {
+		n -= int(f.size - f.opts.maxFileSize)
+		f.size = f.opts.maxFileSize
+		return n, errors.New(http.StatusRequestEntityTooLarge, "MULTIPART", fmt.Sprintf("file %s exceeds %d bytes", f.Filename, f.opts.maxFileSize))
+	}
+	if err != nil && err != io.EOF {
+		err = multipartError(err)
+	}
+	return n, err
+}
+
+// Size returns the number of bytes which have been read.
+func (f *FormFile) Size() int64 {
+	return f.size
+}
+
+// Spool reads the rest of the file into memory, or spills it to a temporary
+// file if it exceeds the memory limit, the caller must close the returned file.
+func (f *FormFile) Spool() (multipart.File, error) {
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, f, f.opts.memory+1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if n <= f.opts.memory {
+		return memoryFile{bytes.NewReader(buf.Bytes())}, nil
+	}
+	file, err := os.CreateTemp(f.opts.tempDir, "multipart-")
+	if err != nil {
+		return nil, err
+	}
+	tf := &tempFile{File: file}
+	if _, err = io.Copy(file, io.MultiReader(&buf, f)); err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		tf.Close()
+		return nil, err
+	}
+	return tf, nil
+}
+
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error { return nil }
+
+// tempFile is a temporary file which is removed on close.
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	if rerr := os.Remove(f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+func matchContentType(contentType string, patterns []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if pattern == mediaType || pattern == "*/*" {
+			return true
+		}
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, pattern[:len(pattern)-1]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/api/httpbody"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+func newMultipartRequest(t *testing.T, files map[string]string) *http.Request {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	_ = mw.WriteField("title", "kratos")
+	for name, content := range files {
+		fw, err := mw.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = fw.Write([]byte(content))
+	}
+	_ = mw.WriteField("tag", "go")
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/upload", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestMultipart(t *testing.T) {
+	png := "\x89PNG\x0D\x0A\x1A\x0A" + strings.Repeat("p", 64)
+	req := newMultipartRequest(t, map[string]string{"kratos.png": png})
+	mr, err := newMultipartReader(req, SpoolMemory(16), SpoolDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := mr.NextFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.FieldName != "file" || f.Filename != "kratos.png" || f.ContentType != "image/png" {
+		t.Errorf("unexpected file: %+v", f)
+	}
+	if got := mr.Values().Get("title"); got != "kratos" {
+		t.Errorf("unexpected title: %s", got)
+	}
+	file, err := f.Spool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tf, ok := file.(*tempFile)
+	if !ok {
+		t.Fatalf("expected a temporary file, got %T", file)
+	}
+	data, _ := io.ReadAll(file)
+	if string(data) != png || f.Size() != int64(len(png)) {
+		t.Errorf("unexpected content: %q", data)
+	}
+	_ = file.Close()
+	if _, err = os.Stat(tf.Name()); !os.IsNotExist(err) {
+		t.Errorf("expected the temporary file to be removed: %v", err)
+	}
+	if _, err = mr.NextFile(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+	if got := mr.Values().Get("tag"); got != "go" {
+		t.Errorf("unexpected tag: %s", got)
+	}
+}
+
+func TestMultipartLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		opts  []MultipartOption
+		code  int32
+	}{
+		{"file size", map[string]string{"a.txt": "hello kratos"}, []MultipartOption{MaxFileSize(5)}, http.StatusRequestEntityTooLarge},
+		{"files", map[string]string{"a.txt": "a", "b.txt": "b"}, []MultipartOption{MaxFiles(1)}, http.StatusRequestEntityTooLarge},
+		{"values size", map[string]string{"a.txt": "a"}, []MultipartOption{MaxValuesSize(3)}, http.StatusRequestEntityTooLarge},
+		{"content type", map[string]string{"a.txt": "hello"}, []MultipartOption{AllowContentTypes("image/*")}, http.StatusUnsupportedMediaType},
+	}
+	for _, test := range tests {
+		mr, err := newMultipartReader(newMultipartRequest(t, test.files), test.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for err == nil {
+			var f *FormFile
+			if f, err = mr.NextFile(); err == nil {
+				_, err = io.ReadAll(f)
+			}
+		}
+		if se := errors.FromError(err); se.Code != test.code {
+			t.Errorf("%s: expected %d, got %v", test.name, test.code, err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	if _, err := newMultipartReader(req); !errors.IsBadRequest(err) {
+		t.Errorf("expected bad request, got %v", err)
+	}
+}
+
+func TestFile(t *testing.T) {
+	srv := NewServer()
+	modtime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	srv.Route("/").GET("/download", func(ctx Context) error {
+		return Attachment(ctx, "hello kratos.txt", strings.NewReader("hello kratos"), modtime)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/download", nil)
+	req.Header.Set("Range", "bytes=6-")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "kratos" {
+		t.Fatalf("unexpected response: %d %q", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="hello kratos.txt"` {
+		t.Errorf("unexpected Content-Disposition: %s", got)
+	}
+	etag := w.Header().Get("Etag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/download", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected 304, got %d", w.Code)
+	}
+}
+
+func TestHTTPBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("<svg/>"))
+	req.Header.Set("Content-Type", "image/svg+xml")
+	in := new(httpbody.HttpBody)
+	if err := DefaultRequestDecoder(req, in); err != nil {
+		t.Fatal(err)
+	}
+	if in.ContentType != "image/svg+xml" || string(in.Data) != "<svg/>" {
+		t.Errorf("unexpected body: %+v", in)
+	}
+
+	w := httptest.NewRecorder()
+	if err := DefaultResponseEncoder(w, req, in); err != nil {
+		t.Fatal(err)
+	}
+	if w.Header().Get("Content-Type") != "image/svg+xml" || w.Body.String() != "<svg/>" {
+		t.Errorf("unexpected response: %s %s", w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	data, err := DefaultRequestEncoder(context.Background(), "application/json", in)
+	if err != nil || string(data) != "<svg/>" {
+		t.Errorf("unexpected encoded body: %q %v", data, err)
+	}
+	out := new(httpbody.HttpBody)
+	res := &http.Response{Header: http.Header{"Content-Type": {"image/svg+xml"}}, Body: io.NopCloser(strings.NewReader("<svg/>"))}
+	if err = DefaultResponseDecoder(context.Background(), res, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ContentType != "image/svg+xml" || string(out.Data) != "<svg/>" {
+		t.Errorf("unexpected decoded body: %+v", out)
+	}
+}
@@ -0,0 +1,38 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+)
+
+// NDJSONDecoder decodes a stream of newline delimited JSON values,
+// such as the response body of a large export.
+type NDJSONDecoder struct {
+	r     *bufio.Reader
+	codec encoding.Codec
+}
+
+// NewNDJSONDecoder returns a decoder of the values from r.
+func NewNDJSONDecoder(r io.Reader) *NDJSONDecoder {
+	return &NDJSONDecoder{
+		r:     bufio.NewReader(r),
+		codec: encoding.GetCodec("json"),
+	}
+}
+
+// Decode decodes the next value with the json codec into v, the blank lines
+// are skipped. It returns io.EOF at the end of the stream.
+func (d *NDJSONDecoder) Decode(v interface{}) error {
+	for {
+		line, err := d.r.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			return d.codec.Unmarshal(line, v)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
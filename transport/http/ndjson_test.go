@@ -0,0 +1,31 @@
+package http
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNDJSONDecoder(t *testing.T) {
+	d := NewNDJSONDecoder(strings.NewReader("{\"name\":\"a\"}\n\n{\"name\":\"b\"}\r\n{\"name\":\"c\"}"))
+	var names []string
+	for {
+		var v struct {
+			Name string `json:"name"`
+		}
+		err := d.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, v.Name)
+	}
+	if strings.Join(names, ",") != "a,b,c" {
+		t.Errorf("unexpected values: %v", names)
+	}
+	if err := NewNDJSONDecoder(strings.NewReader("{")).Decode(new(map[string]string)); err == nil {
+		t.Error("expected an unmarshal error")
+	}
+}
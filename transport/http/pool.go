@@ -0,0 +1,272 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"github.com/go-kratos/kratos/v2/metrics"
+)
+
+// WithMaxIdleConnsPerHost with the maximum idle connections to keep per host.
+func WithMaxIdleConnsPerHost(n int) ClientOption {
+	return func(o *clientOptions) {
+		o.maxIdleConnsPerHost = n
+	}
+}
+
+// WithMaxConnsPerHost with the maximum connections per host, zero means no limit.
+func WithMaxConnsPerHost(n int) ClientOption {
+	return func(o *clientOptions) {
+		o.maxConnsPerHost = n
+	}
+}
+
+// WithIdleConnTimeout with the maximum amount of time an idle connection will
+// remain idle before closing itself.
+func WithIdleConnTimeout(d time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.idleConnTimeout = d
+	}
+}
+
+// WithDialTimeout with the maximum amount of time a dial will wait for a connect to complete.
+func WithDialTimeout(d time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.dialTimeout = d
+	}
+}
+
+// WithKeepAlive with the interval between keep-alive probes of the connections.
+func WithKeepAlive(d time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.keepAlive = d
+	}
+}
+
+// WithTLSHandshakeTimeout with the maximum amount of time waiting for a TLS handshake.
+func WithTLSHandshakeTimeout(d time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.tlsHandshakeTimeout = d
+	}
+}
+
+// WithInflightRequests with the gauge of the in-flight requests per host,
+// a request is in flight until its response body is closed.
+func WithInflightRequests(g metrics.Gauge) ClientOption {
+	return func(o *clientOptions) {
+		o.inflight = g
+	}
+}
+
+// WithConnections with the gauge of the open connections per host.
+func WithConnections(g metrics.Gauge) ClientOption {
+	return func(o *clientOptions) {
+		o.connections = g
+	}
+}
+
+// buildTransport returns the transport of the client, the *http.Transport
+// is cloned so that http.DefaultTransport or the given one is never mutated.
+func (o *clientOptions) buildTransport() (http.RoundTripper, error) {
+	var rt http.RoundTripper
+	if o.h2c && o.tlsConf == nil {
+		if o.transport != nil {
+			return nil, errors.New("[http client] WithH2C can't be used with WithTransport")
+		}
+		dialer := &net.Dialer{Timeout: o.dialTimeout, KeepAlive: o.keepAlive}
+		rt = &http2.Transport{
+			AllowHTTP: true,
+			// the DialTLS has no ctx of the request, so the dial is canceled with the client.
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				network, addr = o.dialAddr(network, addr)
+				conn, err := dialer.DialContext(o.ctx, network, addr)
+				if err != nil {
+					return nil, err
+				}
+				return o.trackConn(addr, conn), nil
+			},
+		}
+	} else {
+		rt = o.transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		if tr, ok := rt.(*http.Transport); ok {
+			var err error
+			if rt, err = o.configureTransport(tr.Clone()); err != nil {
+				return nil, err
+			}
+		} else if o.socket != "" {
+			return nil, errors.New("[http client] the unix socket endpoint requires the transport of *http.Transport")
+		}
+	}
+	if _, ok := rt.(*http.Transport); !ok && (o.proxy != "" || o.noProxy != nil) {
+		return nil, errors.New("[http client] WithProxy and WithNoProxy require the transport of *http.Transport")
+	}
+	if o.inflight != nil {
+		rt = &inflightTransport{next: rt, inflight: o.inflight}
+	}
+	return rt, nil
+}
+
+func (o *clientOptions) configureTransport(tr *http.Transport) (*http.Transport, error) {
+	if o.tlsConf != nil {
+		tr.TLSClientConfig = o.tlsConf
+	}
+	if o.maxIdleConnsPerHost > 0 {
+		tr.MaxIdleConnsPerHost = o.maxIdleConnsPerHost
+	}
+	if o.maxConnsPerHost > 0 {
+		tr.MaxConnsPerHost = o.maxConnsPerHost
+	}
+	if o.idleConnTimeout > 0 {
+		tr.IdleConnTimeout = o.idleConnTimeout
+	}
+	if o.tlsHandshakeTimeout > 0 {
+		tr.TLSHandshakeTimeout = o.tlsHandshakeTimeout
+	}
+	proxy := tr.Proxy
+	if o.socket != "" {
+		proxy = nil
+	} else if o.proxy != "" || o.noProxy != nil {
+		var err error
+		if proxy, err = o.proxyFunc(); err != nil {
+			return nil, err
+		}
+	}
+	tr.Proxy = func(req *http.Request) (*url.URL, error) {
+		// the unix sockets are connected directly.
+		if _, ok := unixSocket(req.URL.Host); ok || proxy == nil {
+			return nil, nil
+		}
+		return proxy(req)
+	}
+	dial := tr.DialContext
+	if dial == nil || o.dialTimeout > 0 || o.keepAlive != 0 {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		if o.dialTimeout > 0 {
+			dialer.Timeout = o.dialTimeout
+		}
+		if o.keepAlive != 0 {
+			dialer.KeepAlive = o.keepAlive
+		}
+		dial = dialer.DialContext
+	}
+	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		network, addr = o.dialAddr(network, addr)
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return o.trackConn(addr, conn), nil
+	}
+	return tr, nil
+}
+
+// dialAddr returns the address to dial, which is the unix socket of the endpoint,
+// or of the node picked by the selector, see unixHost.
+func (o *clientOptions) dialAddr(network, addr string) (string, string) {
+	if o.socket != "" {
+		return "unix", o.socket
+	}
+	if socket, ok := unixSocket(addr); ok {
+		return "unix", socket
+	}
+	return network, addr
+}
+
+// unixHostSuffix is the suffix of the hosts which the unix sockets are encoded into,
+// so that the connections to the different sockets are pooled separately.
+const unixHostSuffix = ".unix.localhost"
+
+// unixHost returns the host of the requests sent to the unix socket.
+func unixHost(socket string) string {
+	return hex.EncodeToString([]byte(socket)) + unixHostSuffix
+}
+
+// unixSocket returns the unix socket of the host or address encoded by unixHost.
+func unixSocket(addr string) (string, bool) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if !strings.HasSuffix(host, unixHostSuffix) {
+		return "", false
+	}
+	socket, err := hex.DecodeString(strings.TrimSuffix(host, unixHostSuffix))
+	if err != nil || len(socket) == 0 {
+		return "", false
+	}
+	return string(socket), true
+}
+
+func (o *clientOptions) trackConn(addr string, conn net.Conn) net.Conn {
+	if o.connections == nil {
+		return conn
+	}
+	g := o.connections.With(addr)
+	g.Add(1)
+	return &trackedConn{Conn: conn, gauge: g}
+}
+
+type trackedConn struct {
+	net.Conn
+	once  sync.Once
+	gauge metrics.Gauge
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(func() { c.gauge.Sub(1) })
+	return c.Conn.Close()
+}
+
+type inflightTransport struct {
+	next     http.RoundTripper
+	inflight metrics.Gauge
+}
+
+func (t *inflightTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	g := t.inflight.With(req.URL.Host)
+	g.Add(1)
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		g.Sub(1)
+		return nil, err
+	}
+	if res.StatusCode == http.StatusSwitchingProtocols {
+		// the body of an upgraded connection is writable, which must not be wrapped.
+		g.Sub(1)
+		return res, nil
+	}
+	res.Body = &inflightBody{ReadCloser: res.Body, gauge: g}
+	return res, nil
+}
+
+// CloseIdleConnections closes the idle connections of the underlying transport.
+func (t *inflightTransport) CloseIdleConnections() {
+	if c, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+type inflightBody struct {
+	io.ReadCloser
+	once  sync.Once
+	gauge metrics.Gauge
+}
+
+func (b *inflightBody) Close() error {
+	b.once.Do(func() { b.gauge.Sub(1) })
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/metrics"
+)
+
+type mockGauge struct {
+	mu     *sync.Mutex
+	lvs    []string
+	values map[string]float64
+}
+
+func newMockGauge() *mockGauge {
+	return &mockGauge{mu: new(sync.Mutex), values: make(map[string]float64)}
+}
+
+func (g *mockGauge) With(lvs ...string) metrics.Gauge {
+	return &mockGauge{mu: g.mu, lvs: lvs, values: g.values}
+}
+
+func (g *mockGauge) Set(value float64) { g.Add(value - g.get(strings.Join(g.lvs, ","))) }
+func (g *mockGauge) Sub(delta float64) { g.Add(-delta) }
+func (g *mockGauge) Add(delta float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[strings.Join(g.lvs, ",")] += delta
+}
+
+func (g *mockGauge) get(lv string) float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.values[lv]
+}
+
+func TestBuildTransport(t *testing.T) {
+	conf := &tls.Config{ServerName: "kratos"}
+	client, err := NewClient(context.Background(),
+		WithTLSConfig(conf),
+		WithMaxIdleConnsPerHost(10),
+		WithMaxConnsPerHost(20),
+		WithIdleConnTimeout(time.Minute),
+		WithTLSHandshakeTimeout(time.Second),
+		WithDialTimeout(time.Second),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if http.DefaultTransport.(*http.Transport).TLSClientConfig == conf {
+		t.Error("http.DefaultTransport must not be mutated")
+	}
+	tr, ok := client.cc.Transport.(*http.Transport)
+	if !ok || tr == http.DefaultTransport {
+		t.Fatalf("expected a cloned transport, got %T", client.cc.Transport)
+	}
+	if tr.TLSClientConfig != conf || tr.MaxIdleConnsPerHost != 10 || tr.MaxConnsPerHost != 20 ||
+		tr.IdleConnTimeout != time.Minute || tr.TLSHandshakeTimeout != time.Second || tr.DialContext == nil {
+		t.Errorf("unexpected transport: %+v", tr)
+	}
+}
+
+func TestTransportStats(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	inflight, conns := newMockGauge(), newMockGauge()
+	client, err := NewClient(context.Background(), WithEndpoint(host), WithInflightRequests(inflight), WithConnections(conns))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inflight.get(host) != 1 || conns.get(host) != 1 {
+		t.Errorf("unexpected stats: %v %v", inflight.get(host), conns.get(host))
+	}
+	_, _ = io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if inflight.get(host) != 0 {
+		t.Errorf("unexpected in-flight requests: %v", inflight.get(host))
+	}
+	// the connection is put back to the idle pool asynchronously.
+	for i := 0; i < 100 && conns.get(host) != 0; i++ {
+		client.cc.CloseIdleConnections()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if conns.get(host) != 0 {
+		t.Errorf("unexpected connections: %v", conns.get(host))
+	}
+}
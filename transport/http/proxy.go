@@ -0,0 +1,53 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// WithProxy with the proxy of the requests, e.g. http://proxy:3128 or socks5://proxy:1080,
+// which overrides the HTTP_PROXY and HTTPS_PROXY environment variables.
+// It requires the transport of *http.Transport, and the unix sockets are connected directly.
+func WithProxy(proxyURL string) ClientOption {
+	return func(o *clientOptions) {
+		o.proxy = proxyURL
+	}
+}
+
+// WithNoProxy with the hosts which are connected directly, in the format of NO_PROXY,
+// e.g. "10.0.0.0/8,.svc.cluster.local", which overrides the NO_PROXY environment variable.
+// The hosts are matched against the addresses of the nodes picked by the selector.
+func WithNoProxy(hosts ...string) ClientOption {
+	return func(o *clientOptions) {
+		o.noProxy = append(o.noProxy, hosts...)
+	}
+}
+
+// proxyFunc returns the proxy function of the transport, the environment variables
+// are used for the settings that are not configured by the options.
+func (o *clientOptions) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	conf := httpproxy.FromEnvironment()
+	if o.proxy != "" {
+		u, err := url.Parse(o.proxy)
+		if err != nil {
+			return nil, err
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme: %s", o.proxy)
+		}
+		conf.HTTPProxy, conf.HTTPSProxy = o.proxy, o.proxy
+	}
+	if o.noProxy != nil {
+		conf.NoProxy = strings.Join(o.noProxy, ",")
+	}
+	proxy := conf.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}, nil
+}
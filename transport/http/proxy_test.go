@@ -0,0 +1,206 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+func TestWithProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		_, _ = w.Write([]byte(`{"name":"kratos"}`))
+	}))
+	defer proxy.Close()
+
+	client, err := NewClient(context.Background(), WithEndpoint("greeter.test:8000"), WithProxy(proxy.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := make(map[string]string)
+	if err = client.Invoke(context.Background(), http.MethodGet, "/hello", nil, &reply); err != nil {
+		t.Fatal(err)
+	}
+	if proxied != "http://greeter.test:8000/hello" || reply["name"] != "kratos" {
+		t.Errorf("unexpected proxied request: %s %v", proxied, reply)
+	}
+
+	if _, err = NewClient(context.Background(), WithProxy("ftp://proxy:21")); err == nil {
+		t.Error("expected an error of the unsupported proxy scheme")
+	}
+}
+
+func TestWithNoProxy(t *testing.T) {
+	o := &clientOptions{proxy: "socks5://proxy:1080", noProxy: []string{".svc.cluster.local", "10.0.0.0/8"}}
+	proxy, err := o.proxyFunc()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url   string
+		proxy string
+	}{
+		{"http://greeter.default.svc.cluster.local:8000/hello", ""},
+		{"http://10.0.0.1:8000/hello", ""},
+		{"http://example.com/hello", "socks5://proxy:1080"},
+		{"https://example.com/hello", "socks5://proxy:1080"},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(http.MethodGet, test.url, nil)
+		u, err := proxy(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := ""
+		if u != nil {
+			got = u.String()
+		}
+		if got != test.proxy {
+			t.Errorf("%s: expected proxy %q, got %v", test.url, test.proxy, u)
+		}
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	// the path of unix socket is limited to about 100 bytes.
+	dir, err := os.MkdirTemp("", "kratos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "sidecar.sock")
+	lis, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	})}
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), WithEndpoint("unix://"+sock), WithProxy("http://proxy.test:3128"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	reply := make(map[string]string)
+	if err = client.Invoke(context.Background(), http.MethodGet, "/hello", nil, &reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply["path"] != "/hello" {
+		t.Errorf("unexpected reply: %v", reply)
+	}
+	// the requests made by Do are sent to the socket as well.
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/world", nil)
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status: %d", res.StatusCode)
+	}
+
+	if _, err = parseTarget("unix://", true); err == nil || !strings.Contains(err.Error(), "unix socket") {
+		t.Errorf("expected an error of the missing socket path, got %v", err)
+	}
+	// the socket is not resolved by the discovery.
+	_, err = NewClient(context.Background(), WithEndpoint("unix://"+sock), WithDiscovery(&unixDiscovery{socket: sock}))
+	if err == nil || !strings.Contains(err.Error(), "can't be resolved by the discovery") {
+		t.Errorf("expected an error of the unix socket with the discovery, got %v", err)
+	}
+}
+
+type unixDiscovery struct {
+	socket string
+}
+
+func (d *unixDiscovery) GetService(ctx context.Context, serviceName string) ([]*registry.ServiceInstance, error) {
+	return nil, nil
+}
+
+func (d *unixDiscovery) Watch(ctx context.Context, serviceName string) (registry.Watcher, error) {
+	return &unixWatcher{ctx: ctx, socket: d.socket}, nil
+}
+
+type unixWatcher struct {
+	ctx    context.Context
+	socket string
+	done   bool
+}
+
+func (w *unixWatcher) Next() ([]*registry.ServiceInstance, error) {
+	if w.done {
+		<-w.ctx.Done()
+		return nil, w.ctx.Err()
+	}
+	w.done = true
+	return []*registry.ServiceInstance{{ID: "1", Name: "sidecar", Endpoints: []string{"unix://" + w.socket}}}, nil
+}
+
+func (w *unixWatcher) Stop() error { return nil }
+
+func TestUnixSocketDiscovery(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kratos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "sidecar.sock")
+	lis, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"host":"` + r.Host + `"}`))
+	})}
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client, err := NewClient(ctx,
+		WithEndpoint("discovery:///sidecar"),
+		WithDiscovery(&unixDiscovery{socket: sock}),
+		WithBlock(),
+		WithProxy("http://proxy.test:3128"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	reply := make(map[string]string)
+	if err = client.Invoke(context.Background(), http.MethodGet, "/hello", nil, &reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply["host"] != "localhost" {
+		t.Errorf("unexpected reply: %v", reply)
+	}
+	if socket, ok := unixSocket(unixHost(sock) + ":80"); !ok || socket != sock {
+		t.Errorf("unexpected socket of the host: %s", socket)
+	}
+	if _, ok := unixSocket("127.0.0.1:80"); ok {
+		t.Error("expected no socket of the TCP address")
+	}
+}
+
+func TestProxyTransport(t *testing.T) {
+	if _, err := NewClient(context.Background(), WithTransport(&mockRoundTripper{}), WithProxy("http://proxy.test:3128")); err == nil {
+		t.Error("expected an error of the proxy with the custom transport")
+	}
+	if _, err := NewClient(context.Background(), WithTransport(&mockRoundTripper{}), WithEndpoint("unix:///var/run/sidecar.sock")); err == nil {
+		t.Error("expected an error of the unix socket with the custom transport")
+	}
+	if _, err := NewClient(context.Background(), WithTransport(&http.Transport{}), WithProxy("http://proxy.test:3128")); err != nil {
+		t.Errorf("expected the proxy with *http.Transport, got %v", err)
+	}
+}
@@ -33,6 +33,17 @@ func parseTarget(endpoint string, insecure bool) (*Target, error) {
 		return nil, err
 	}
 	target := &Target{Scheme: u.Scheme, Authority: u.Host}
+	if u.Scheme == "unix" {
+		// unix:///var/run/app.sock or unix:app.sock
+		target.Endpoint = u.Path
+		if u.Opaque != "" {
+			target.Endpoint = u.Opaque
+		}
+		if target.Endpoint == "" {
+			return nil, errors.New("missing unix socket path: " + endpoint)
+		}
+		return target, nil
+	}
 	if len(u.Path) > 1 {
 		target.Endpoint = u.Path[1:]
 	}
@@ -118,7 +129,12 @@ func (r *resolver) update(services []*registry.ServiceInstance) bool {
 			continue
 		}
 		if ept == "" {
-			continue
+			// the instances of the sidecars, e.g. unix:///var/run/helloworld.sock
+			socket := unixEndpoint(ins.Endpoints)
+			if socket == "" {
+				continue
+			}
+			ept = unixHost(socket)
 		}
 		nodes = append(nodes, selector.NewNode("http", ept, ins))
 	}
@@ -130,6 +146,19 @@ func (r *resolver) update(services []*registry.ServiceInstance) bool {
 	return true
 }
 
+// unixEndpoint returns the path of the first unix socket endpoint.
+func unixEndpoint(endpoints []string) string {
+	for _, e := range endpoints {
+		if !strings.HasPrefix(e, "unix:") {
+			continue
+		}
+		if target, err := parseTarget(e, true); err == nil {
+			return target.Endpoint
+		}
+	}
+	return ""
+}
+
 func (r *resolver) Close() error {
 	return r.watcher.Stop()
 }
@@ -46,6 +46,14 @@ func TestParseTarget(t *testing.T) {
 		t.Errorf("expect %v, got %v", &Target{Scheme: "https", Authority: "127.0.0.1:8000"}, target)
 	}
 
+	target, err = parseTarget("unix:///var/run/demo.sock", true)
+	if err != nil {
+		t.Errorf("expect %v, got %v", nil, err)
+	}
+	if !reflect.DeepEqual(&Target{Scheme: "unix", Endpoint: "/var/run/demo.sock"}, target) {
+		t.Errorf("expect %v, got %v", &Target{Scheme: "unix", Endpoint: "/var/run/demo.sock"}, target)
+	}
+
 	target, err = parseTarget("127.0.0.1:8000", false)
 	if err != nil {
 		t.Errorf("expect %v, got %v", nil, err)
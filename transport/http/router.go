@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"path"
 	"sync"
+	"time"
 )
 
 // WalkRouteFunc is the type of the function called for each route visited by Walk.
@@ -24,6 +25,7 @@ type Router struct {
 	pool    sync.Pool
 	srv     *Server
 	filters []FilterFunc
+	config  routeConfig
 }
 
 func newRouter(prefix string, srv *Server, filters ...FilterFunc) *Router {
@@ -43,12 +45,34 @@ func (r *Router) Group(prefix string, filters ...FilterFunc) *Router {
 	var newFilters []FilterFunc
 	newFilters = append(newFilters, r.filters...)
 	newFilters = append(newFilters, filters...)
-	return newRouter(path.Join(r.prefix, prefix), r.srv, newFilters...)
+	nr := newRouter(path.Join(r.prefix, prefix), r.srv, newFilters...)
+	nr.config = r.config
+	return nr
+}
+
+// Timeout returns a new router whose routes use the timeout instead of the
+// server timeout, a negative timeout means no timeout.
+func (r *Router) Timeout(timeout time.Duration) *Router {
+	nr := r.Group("")
+	nr.config.timeout = timeout
+	return nr
+}
+
+// MaxBodySize returns a new router whose routes limit the request body
+// to n bytes instead of the server limits, a negative n means no limit.
+func (r *Router) MaxBodySize(n int64) *Router {
+	nr := r.Group("")
+	nr.config.maxBodySize = n
+	return nr
 }
 
 // Handle registers a new route with a matcher for the URL path and method.
 func (r *Router) Handle(method, relativePath string, h HandlerFunc, filters ...FilterFunc) {
 	next := http.Handler(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Context().Value(preflightKey{}) != nil {
+			// the preflight request is not handled by a CORS filter of the route.
+			return
+		}
 		ctx := r.pool.Get().(Context)
 		ctx.Reset(res, req)
 		if err := h(ctx); err != nil {
@@ -59,7 +83,10 @@ func (r *Router) Handle(method, relativePath string, h HandlerFunc, filters ...F
 	}))
 	next = FilterChain(filters...)(next)
 	next = FilterChain(r.filters...)(next)
-	r.srv.router.Handle(path.Join(r.prefix, relativePath), next).Methods(method)
+	route := r.srv.router.Handle(path.Join(r.prefix, relativePath), next).Methods(method)
+	r.srv.routesMu.Lock()
+	r.srv.routes[route] = r.config
+	r.srv.routesMu.Unlock()
 }
 
 // GET registers a new GET route for a path with matching handler in the router.
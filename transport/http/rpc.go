@@ -0,0 +1,573 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+var _ grpc.ServiceRegistrar = (*Server)(nil)
+
+// RegisterService registers a gRPC service and its implementation to be served with
+// the gRPC-Web and Connect protocols, e.g. pb.RegisterGreeterServer(httpSrv, svc).
+// The methods are run through the server middleware with the gRPC full method as
+// the operation, and client-streaming and bidi-streaming methods are not served.
+func (s *Server) RegisterService(desc *grpc.ServiceDesc, impl interface{}) {
+	if impl != nil && desc.HandlerType != nil {
+		ht := reflect.TypeOf(desc.HandlerType).Elem()
+		if st := reflect.TypeOf(impl); !st.Implements(ht) {
+			panic(fmt.Sprintf("http: RegisterService found the handler of type %v that does not satisfy %v", st, ht))
+		}
+	}
+	r := s.Route("/")
+	for _, md := range desc.Methods {
+		method := "/" + desc.ServiceName + "/" + md.MethodName
+		r.POST(method, s.unaryRPC(method, impl, md.Handler))
+	}
+	for _, sd := range desc.Streams {
+		if sd.ClientStreams {
+			continue
+		}
+		method := "/" + desc.ServiceName + "/" + sd.StreamName
+		r.POST(method, s.streamRPC(method, impl, sd.Handler))
+	}
+}
+
+func (s *Server) unaryRPC(method string, impl interface{}, handler func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error)) HandlerFunc {
+	return func(ctx Context) error {
+		call, err := newRPCCall(ctx.Response(), ctx.Request(), method, s.rpcMaxMessageSize(ctx.Request()))
+		if err != nil {
+			return err
+		}
+		defer call.close()
+		SetOperation(ctx, method)
+		c, cancel := call.context(ctx)
+		defer cancel()
+		interceptor := func(c context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			h := ctx.Middleware(func(c context.Context, req interface{}) (interface{}, error) {
+				return handler(c, req)
+			})
+			return h(c, req)
+		}
+		reply, err := handler(impl, c, call.decode, interceptor)
+		if err == nil {
+			err = call.sendMessage(reply)
+		}
+		call.finish(err)
+		return nil
+	}
+}
+
+func (s *Server) streamRPC(method string, impl interface{}, handler grpc.StreamHandler) HandlerFunc {
+	return func(ctx Context) error {
+		call, err := newRPCCall(ctx.Response(), ctx.Request(), method, s.rpcMaxMessageSize(ctx.Request()))
+		if err != nil {
+			return err
+		}
+		defer call.close()
+		SetOperation(ctx, method)
+		c, cancel := call.context(ctx)
+		defer cancel()
+		h := ctx.Middleware(func(c context.Context, _ interface{}) (interface{}, error) {
+			return nil, handler(impl, &rpcServerStream{ctx: c, call: call})
+		})
+		_, err = h(c, nil)
+		call.finish(err)
+		return nil
+	}
+}
+
+// defaultMaxMessageSize is the maximum message size of the calls without a body limit,
+// which is the default of the gRPC servers.
+const defaultMaxMessageSize = 4 << 20
+
+// rpcMaxMessageSize returns the maximum message size of the call, which is the
+// body limit of the route or the server.
+func (s *Server) rpcMaxMessageSize(req *http.Request) int64 {
+	if n := s.bodyLimit(req, s.routeConfig(req)); n > 0 {
+		return n
+	}
+	return defaultMaxMessageSize
+}
+
+type rpcProtocol uint8
+
+const (
+	rpcGRPCWeb rpcProtocol = iota
+	rpcGRPCWebText
+	rpcConnectUnary
+	rpcConnectStream
+)
+
+const (
+	flagCompressed = 0x01
+	flagEndStream  = 0x02
+	flagTrailer    = 0x80
+)
+
+// rpcCall is a gRPC-Web or Connect call, it implements grpc.ServerTransportStream
+// so that grpc.SetHeader and grpc.SetTrailer work in the handlers.
+type rpcCall struct {
+	protocol    rpcProtocol
+	method      string
+	codec       encoding.Codec
+	contentType string
+	w           http.ResponseWriter
+	req         *http.Request
+	body        io.Reader
+	closer      io.Closer
+	maxSize     int64
+
+	mu         sync.Mutex
+	header     metadata.MD
+	trailer    metadata.MD
+	headerSent bool
+	received   bool
+	reply      []byte
+}
+
+func newRPCCall(w http.ResponseWriter, req *http.Request, method string, maxSize int64) (*rpcCall, error) {
+	contentType := strings.ToLower(req.Header.Get("Content-Type"))
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = strings.TrimSpace(contentType[:i])
+	}
+	c := &rpcCall{method: method, w: w, req: req, body: req.Body, maxSize: maxSize, header: metadata.MD{}, trailer: metadata.MD{}}
+	var subtype, prefix string
+	switch {
+	case contentType == "application/grpc-web-text" || strings.HasPrefix(contentType, "application/grpc-web-text+"):
+		c.protocol, prefix = rpcGRPCWebText, "application/grpc-web-text+"
+		subtype = strings.TrimPrefix(strings.TrimPrefix(contentType, "application/grpc-web-text"), "+")
+		c.body = base64.NewDecoder(base64.StdEncoding, req.Body)
+	case contentType == "application/grpc-web" || strings.HasPrefix(contentType, "application/grpc-web+"):
+		c.protocol, prefix = rpcGRPCWeb, "application/grpc-web+"
+		subtype = strings.TrimPrefix(strings.TrimPrefix(contentType, "application/grpc-web"), "+")
+	case strings.HasPrefix(contentType, "application/connect+"):
+		c.protocol, prefix = rpcConnectStream, "application/connect+"
+		subtype = strings.TrimPrefix(contentType, "application/connect+")
+	case contentType == "application/proto" || contentType == "application/json":
+		c.protocol, prefix = rpcConnectUnary, "application/"
+		subtype = strings.TrimPrefix(contentType, "application/")
+		body, err := decompressBody(req)
+		if err != nil {
+			return nil, err
+		}
+		c.body, c.closer = body, body
+	default:
+		return nil, errors.New(http.StatusUnsupportedMediaType, "CODEC", fmt.Sprintf("unsupported Content-Type: %s", contentType))
+	}
+	if subtype == "" {
+		subtype = "proto"
+	}
+	if c.codec = encoding.GetCodec(subtype); c.codec == nil {
+		c.close()
+		return nil, errors.New(http.StatusUnsupportedMediaType, "CODEC", fmt.Sprintf("unregister Content-Type: %s", contentType))
+	}
+	c.contentType = prefix + subtype
+	return c, nil
+}
+
+// close releases the decompressors of the request body.
+func (c *rpcCall) close() {
+	if c.closer != nil {
+		_ = c.closer.Close()
+	}
+}
+
+// context returns the context of the call with the incoming metadata and the deadline
+// of the grpc-timeout or connect-timeout-ms header.
+func (c *rpcCall) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	md := metadata.MD{}
+	for k, vs := range c.req.Header {
+		k = strings.ToLower(k)
+		for _, v := range vs {
+			if strings.HasSuffix(k, "-bin") {
+				if b, err := decodeBinHeader(v); err == nil {
+					v = string(b)
+				}
+			}
+			md.Append(k, v)
+		}
+	}
+	ctx = metadata.NewIncomingContext(ctx, md)
+	ctx = grpc.NewContextWithServerTransportStream(ctx, c)
+	if timeout, ok := c.timeout(); ok {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+func (c *rpcCall) timeout() (time.Duration, bool) {
+	if v := c.req.Header.Get("Connect-Timeout-Ms"); v != "" {
+		ms, err := strconv.ParseInt(v, 10, 64)
+		return time.Duration(ms) * time.Millisecond, err == nil
+	}
+	v := c.req.Header.Get("Grpc-Timeout")
+	if len(v) < 2 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	units := map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second, 'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond}
+	unit, ok := units[v[len(v)-1]]
+	return time.Duration(n) * unit, ok
+}
+
+func (c *rpcCall) decode(v interface{}) error {
+	data, err := c.readMessage()
+	if err != nil {
+		return err
+	}
+	if err = c.codec.Unmarshal(data, v); err != nil {
+		return errors.BadRequest("CODEC", fmt.Sprintf("body unmarshal %s", err.Error()))
+	}
+	return nil
+}
+
+func (c *rpcCall) readMessage() ([]byte, error) {
+	if c.received {
+		return nil, io.EOF
+	}
+	c.received = true
+	if c.protocol == rpcConnectUnary {
+		// the body may be decompressed, so it is limited as well as the framed messages.
+		data, err := io.ReadAll(io.LimitReader(c.body, c.maxSize+1))
+		if err != nil {
+			if se := new(errors.Error); errors.As(err, &se) {
+				return nil, se
+			}
+			return nil, errors.BadRequest("CODEC", err.Error())
+		}
+		if int64(len(data)) > c.maxSize {
+			return nil, errMessageTooLarge(c.maxSize)
+		}
+		return data, nil
+	}
+	var prefix [5]byte
+	if _, err := io.ReadFull(c.body, prefix[:]); err != nil {
+		return nil, errors.BadRequest("CODEC", fmt.Sprintf("read message %s", err.Error()))
+	}
+	// the length is checked before allocating, since it is declared by the client.
+	size := int64(binary.BigEndian.Uint32(prefix[1:]))
+	if size > c.maxSize {
+		return nil, errMessageTooLarge(c.maxSize)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(c.body, data); err != nil {
+		return nil, errors.BadRequest("CODEC", fmt.Sprintf("read message %s", err.Error()))
+	}
+	if prefix[0]&flagCompressed == 0 {
+		return data, nil
+	}
+	name := c.req.Header.Get("Grpc-Encoding")
+	if c.protocol == rpcConnectStream {
+		name = c.req.Header.Get("Connect-Content-Encoding")
+	}
+	compressor := encoding.GetCompressor(strings.ToLower(name))
+	if compressor == nil {
+		return nil, errors.New(http.StatusUnsupportedMediaType, "CODEC", fmt.Sprintf("unregister message encoding: %s", name))
+	}
+	r, err := compressor.Decompress(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.BadRequest("CODEC", fmt.Sprintf("message decompress %s", err.Error()))
+	}
+	data, err = io.ReadAll(io.LimitReader(r, c.maxSize+1))
+	if rc, ok := r.(io.Closer); ok {
+		_ = rc.Close()
+	}
+	if err != nil {
+		return nil, errors.BadRequest("CODEC", fmt.Sprintf("message decompress %s", err.Error()))
+	}
+	if int64(len(data)) > c.maxSize {
+		return nil, errMessageTooLarge(c.maxSize)
+	}
+	return data, nil
+}
+
+func errMessageTooLarge(n int64) *errors.Error {
+	return errors.New(http.StatusRequestEntityTooLarge, "MESSAGE_TOO_LARGE", fmt.Sprintf("message exceeds %d bytes", n))
+}
+
+func (c *rpcCall) sendMessage(v interface{}) error {
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.protocol == rpcConnectUnary {
+		// the unary response is written with the trailers when the call finishes.
+		c.reply = data
+		return nil
+	}
+	c.writeHeader()
+	if err = c.writeFrame(0, data); err != nil {
+		return err
+	}
+	if f, ok := c.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// writeHeader writes the response headers of a streaming response, the caller must hold the lock.
+func (c *rpcCall) writeHeader() {
+	if c.headerSent {
+		return
+	}
+	c.headerSent = true
+	header := c.w.Header()
+	setMetadataHeader(header, "", c.header)
+	header.Set("Content-Type", c.contentType)
+	header.Del("Content-Length")
+	c.w.WriteHeader(http.StatusOK)
+}
+
+func (c *rpcCall) writeFrame(flag byte, data []byte) error {
+	frame := make([]byte, 5+len(data))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(data)))
+	copy(frame[5:], data)
+	if c.protocol == rpcGRPCWebText {
+		frame = []byte(base64.StdEncoding.EncodeToString(frame))
+	}
+	_, err := c.w.Write(frame)
+	return err
+}
+
+// finish writes the status of the call, which is encoded as a trailer frame by gRPC-Web,
+// as an end-of-stream frame by the Connect streaming protocol and as a JSON error body
+// by the Connect unary protocol.
+func (c *rpcCall) finish(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var se *errors.Error
+	if err != nil {
+		se = errors.FromError(err)
+	}
+	switch c.protocol {
+	case rpcConnectUnary:
+		header := c.w.Header()
+		setMetadataHeader(header, "", c.header)
+		setMetadataHeader(header, "Trailer-", c.trailer)
+		if se == nil {
+			header.Set("Content-Type", c.contentType)
+			c.w.WriteHeader(http.StatusOK)
+			_, _ = c.w.Write(c.reply)
+			return
+		}
+		body, _ := json.Marshal(newConnectError(se))
+		header.Set("Content-Type", "application/json")
+		c.w.WriteHeader(connectStatus(se.GRPCStatus().Code()))
+		_, _ = c.w.Write(body)
+	case rpcConnectStream:
+		c.writeHeader()
+		end := connectEndStream{Metadata: map[string][]string(c.trailer)}
+		if se != nil {
+			end.Error = newConnectError(se)
+		}
+		body, _ := json.Marshal(end)
+		_ = c.writeFrame(flagEndStream, body)
+	default:
+		c.writeHeader()
+		st := status.New(codes.OK, "")
+		if se != nil {
+			st = se.GRPCStatus()
+		}
+		trailer := bytes.NewBuffer(nil)
+		fmt.Fprintf(trailer, "grpc-status: %d\r\n", st.Code())
+		fmt.Fprintf(trailer, "grpc-message: %s\r\n", encodeGRPCMessage(st.Message()))
+		if se != nil {
+			if details, e := proto.Marshal(st.Proto()); e == nil {
+				fmt.Fprintf(trailer, "grpc-status-details-bin: %s\r\n", base64.RawStdEncoding.EncodeToString(details))
+			}
+		}
+		for k, vs := range c.trailer {
+			for _, v := range vs {
+				fmt.Fprintf(trailer, "%s: %s\r\n", k, encodeMetadataValue(k, v))
+			}
+		}
+		_ = c.writeFrame(flagTrailer, trailer.Bytes())
+	}
+	if f, ok := c.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Method returns the gRPC full method of the call.
+func (c *rpcCall) Method() string {
+	return c.method
+}
+
+// SetHeader sets the header metadata, which is sent with the first message.
+func (c *rpcCall) SetHeader(md metadata.MD) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.headerSent {
+		return errors.New(http.StatusInternalServerError, "RPC", "the headers have been sent")
+	}
+	c.header = metadata.Join(c.header, md)
+	return nil
+}
+
+// SendHeader sends the header metadata.
+func (c *rpcCall) SendHeader(md metadata.MD) error {
+	if err := c.SetHeader(md); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.protocol != rpcConnectUnary {
+		c.writeHeader()
+	}
+	return nil
+}
+
+// SetTrailer sets the trailer metadata, which is sent when the call finishes.
+func (c *rpcCall) SetTrailer(md metadata.MD) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.trailer = metadata.Join(c.trailer, md)
+	return nil
+}
+
+type rpcServerStream struct {
+	ctx  context.Context
+	call *rpcCall
+}
+
+func (s *rpcServerStream) SetHeader(md metadata.MD) error  { return s.call.SetHeader(md) }
+func (s *rpcServerStream) SendHeader(md metadata.MD) error { return s.call.SendHeader(md) }
+func (s *rpcServerStream) SetTrailer(md metadata.MD)       { _ = s.call.SetTrailer(md) }
+func (s *rpcServerStream) Context() context.Context        { return s.ctx }
+func (s *rpcServerStream) SendMsg(m interface{}) error     { return s.call.sendMessage(m) }
+func (s *rpcServerStream) RecvMsg(m interface{}) error     { return s.call.decode(m) }
+
+type connectError struct {
+	Code    string               `json:"code"`
+	Message string               `json:"message,omitempty"`
+	Details []connectErrorDetail `json:"details,omitempty"`
+}
+
+type connectErrorDetail struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type connectEndStream struct {
+	Error    *connectError       `json:"error,omitempty"`
+	Metadata map[string][]string `json:"metadata,omitempty"`
+}
+
+func newConnectError(se *errors.Error) *connectError {
+	st := se.GRPCStatus()
+	e := &connectError{Code: connectCode(st.Code().String()), Message: st.Message()}
+	for _, detail := range st.Proto().GetDetails() {
+		typ := detail.GetTypeUrl()
+		if i := strings.LastIndex(typ, "/"); i >= 0 {
+			typ = typ[i+1:]
+		}
+		e.Details = append(e.Details, connectErrorDetail{Type: typ, Value: base64.RawStdEncoding.EncodeToString(detail.GetValue())})
+	}
+	return e
+}
+
+// connectCode converts the gRPC code name to the Connect one, e.g. InvalidArgument to invalid_argument.
+func connectCode(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// connectStatuses are the HTTP statuses of the Connect codes of the unary errors.
+var connectStatuses = map[codes.Code]int{
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+// connectStatus returns the HTTP status of the Connect code, see
+// https://connectrpc.com/docs/protocol#error-codes.
+func connectStatus(code codes.Code) int {
+	if s, ok := connectStatuses[code]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+func setMetadataHeader(header http.Header, prefix string, md metadata.MD) {
+	for k, vs := range md {
+		for _, v := range vs {
+			header.Add(prefix+k, encodeMetadataValue(k, v))
+		}
+	}
+}
+
+func encodeMetadataValue(k, v string) string {
+	if strings.HasSuffix(k, "-bin") {
+		return base64.RawStdEncoding.EncodeToString([]byte(v))
+	}
+	return v
+}
+
+func decodeBinHeader(v string) ([]byte, error) {
+	if len(v)%4 == 0 {
+		return base64.StdEncoding.DecodeString(v)
+	}
+	return base64.RawStdEncoding.DecodeString(v)
+}
+
+// encodeGRPCMessage percent-encodes the grpc-message as the gRPC spec requires.
+func encodeGRPCMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		if c := msg[i]; c >= ' ' && c <= '~' && c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", msg[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,326 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/internal/testdata/binding"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+type echoService interface {
+	Echo(context.Context, *binding.HelloRequest) (*binding.HelloRequest, error)
+}
+
+type echoServer struct{}
+
+func (echoServer) Echo(ctx context.Context, in *binding.HelloRequest) (*binding.HelloRequest, error) {
+	if in.Name == "error" {
+		return nil, errors.NotFound("USER_NOT_FOUND", "user not found")
+	}
+	if in.Name == "grpc-error" {
+		// the code of the gRPC style isn't a valid HTTP status.
+		return nil, errors.New(int(codes.NotFound), "USER_NOT_FOUND", "user not found")
+	}
+	return &binding.HelloRequest{Name: "hello " + in.Name}, nil
+}
+
+var echoServiceDesc = grpc.ServiceDesc{
+	ServiceName: "test.Echo",
+	HandlerType: (*echoService)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Echo",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := new(binding.HelloRequest)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Echo/Echo"}
+			return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.(echoService).Echo(ctx, req.(*binding.HelloRequest))
+			})
+		},
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "List",
+		ServerStreams: true,
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			in := new(binding.HelloRequest)
+			if err := stream.RecvMsg(in); err != nil {
+				return err
+			}
+			for i := 0; i < 2; i++ {
+				if err := stream.SendMsg(&binding.HelloRequest{Name: in.Name}); err != nil {
+					return err
+				}
+			}
+			return errors.ServiceUnavailable("DONE", "stream done")
+		},
+	}},
+}
+
+func newRPCServer(operations *[]string) *Server {
+	srv := NewServer(Middleware(func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if tr, ok := transport.FromServerContext(ctx); ok {
+				*operations = append(*operations, tr.Operation())
+			}
+			return handler(ctx, req)
+		}
+	}))
+	srv.RegisterService(&echoServiceDesc, echoServer{})
+	return srv
+}
+
+func envelope(flag byte, data []byte) []byte {
+	frame := make([]byte, 5+len(data))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(data)))
+	copy(frame[5:], data)
+	return frame
+}
+
+func readFrames(t *testing.T, r io.Reader) (flags []byte, frames [][]byte) {
+	for {
+		var prefix [5]byte
+		if _, err := io.ReadFull(r, prefix[:]); err == io.EOF {
+			return
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		data := make([]byte, binary.BigEndian.Uint32(prefix[1:]))
+		if _, err := io.ReadFull(r, data); err != nil {
+			t.Fatal(err)
+		}
+		flags = append(flags, prefix[0])
+		frames = append(frames, data)
+	}
+}
+
+func TestGRPCWeb(t *testing.T) {
+	var operations []string
+	srv := newRPCServer(&operations)
+	in, _ := proto.Marshal(&binding.HelloRequest{Name: "kratos"})
+
+	for _, text := range []bool{false, true} {
+		body := envelope(0, in)
+		contentType := "application/grpc-web+proto"
+		if text {
+			body = []byte(base64.StdEncoding.EncodeToString(body))
+			contentType = "application/grpc-web-text"
+		}
+		req := httptest.NewRequest(http.MethodPost, "/test.Echo/Echo", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var res io.Reader = w.Body
+		if text {
+			if got := w.Header().Get("Content-Type"); got != "application/grpc-web-text+proto" {
+				t.Errorf("unexpected Content-Type: %s", got)
+			}
+			res = base64.NewDecoder(base64.StdEncoding, w.Body)
+		}
+		flags, frames := readFrames(t, res)
+		if len(frames) != 2 || flags[0] != 0 || flags[1] != flagTrailer {
+			t.Fatalf("unexpected frames: %v", flags)
+		}
+		reply := new(binding.HelloRequest)
+		if err := proto.Unmarshal(frames[0], reply); err != nil {
+			t.Fatal(err)
+		}
+		if reply.Name != "hello kratos" {
+			t.Errorf("unexpected reply: %s", reply.Name)
+		}
+		if !strings.Contains(string(frames[1]), "grpc-status: 0\r\n") {
+			t.Errorf("unexpected trailers: %q", frames[1])
+		}
+	}
+	if len(operations) != 2 || operations[0] != "/test.Echo/Echo" {
+		t.Errorf("unexpected middleware operations: %v", operations)
+	}
+
+	in, _ = proto.Marshal(&binding.HelloRequest{Name: "error"})
+	req := httptest.NewRequest(http.MethodPost, "/test.Echo/Echo", bytes.NewReader(envelope(0, in)))
+	req.Header.Set("Content-Type", "application/grpc-web")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	flags, frames := readFrames(t, w.Body)
+	if len(frames) != 1 || flags[0] != flagTrailer {
+		t.Fatalf("unexpected frames: %v", flags)
+	}
+	if trailer := string(frames[0]); !strings.Contains(trailer, "grpc-status: 5\r\n") || !strings.Contains(trailer, "grpc-message: user not found\r\n") {
+		t.Errorf("unexpected trailers: %q", trailer)
+	}
+}
+
+func TestConnectUnary(t *testing.T) {
+	var operations []string
+	srv := newRPCServer(&operations)
+
+	req := httptest.NewRequest(http.MethodPost, "/test.Echo/Echo", strings.NewReader(`{"name":"kratos"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	reply := struct {
+		Name string `json:"name"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Name != "hello kratos" {
+		t.Errorf("unexpected reply: %s", reply.Name)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/test.Echo/Echo", strings.NewReader(`{"name":"error"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+	var e connectError
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Code != "not_found" || e.Message != "user not found" || len(e.Details) != 1 || e.Details[0].Type != "google.rpc.ErrorInfo" {
+		t.Errorf("unexpected error: %+v", e)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/test.Echo/Echo", strings.NewReader(`{"name":"grpc-error"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), `"unknown"`) {
+		t.Fatalf("expected the status of the Connect code, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/test.Echo/Echo", strings.NewReader("name=kratos"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected 415, got %d", w.Code)
+	}
+}
+
+func TestConnectStream(t *testing.T) {
+	var operations []string
+	srv := newRPCServer(&operations)
+
+	req := httptest.NewRequest(http.MethodPost, "/test.Echo/List", bytes.NewReader(envelope(0, []byte(`{"name":"kratos"}`))))
+	req.Header.Set("Content-Type", "application/connect+json")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if got := w.Header().Get("Content-Type"); got != "application/connect+json" {
+		t.Errorf("unexpected Content-Type: %s", got)
+	}
+	flags, frames := readFrames(t, w.Body)
+	if len(frames) != 3 || flags[2] != flagEndStream {
+		t.Fatalf("unexpected frames: %v", flags)
+	}
+	var end connectEndStream
+	if err := json.Unmarshal(frames[2], &end); err != nil {
+		t.Fatal(err)
+	}
+	if end.Error == nil || end.Error.Code != "unavailable" {
+		t.Errorf("unexpected end of stream: %s", frames[2])
+	}
+	if len(operations) != 1 || operations[0] != "/test.Echo/List" {
+		t.Errorf("unexpected middleware operations: %v", operations)
+	}
+}
+
+func TestConnectCode(t *testing.T) {
+	for name, want := range map[string]string{
+		"Canceled":         "canceled",
+		"InvalidArgument":  "invalid_argument",
+		"DeadlineExceeded": "deadline_exceeded",
+	} {
+		if got := connectCode(name); got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	}
+}
+
+func TestConnectStatus(t *testing.T) {
+	for code, want := range map[codes.Code]int{
+		codes.Canceled:          499,
+		codes.NotFound:          http.StatusNotFound,
+		codes.ResourceExhausted: http.StatusTooManyRequests,
+		codes.Unauthenticated:   http.StatusUnauthorized,
+		codes.Code(100):         http.StatusInternalServerError,
+	} {
+		if got := connectStatus(code); got != want {
+			t.Errorf("%s: expected %d, got %d", code, want, got)
+		}
+	}
+}
+
+func TestRPCMessageTooLarge(t *testing.T) {
+	var operations []string
+	srv := newRPCServer(&operations)
+	// the prefix declares a message of about 4GB without sending it.
+	frame := []byte{0, 0xff, 0xff, 0xff, 0xff}
+	req := httptest.NewRequest(http.MethodPost, "/test.Echo/Echo", bytes.NewReader(frame))
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	flags, frames := readFrames(t, w.Body)
+	if len(frames) != 1 || flags[0] != flagTrailer {
+		t.Fatalf("unexpected frames: %v", flags)
+	}
+	if trailer := string(frames[0]); !strings.Contains(trailer, fmt.Sprintf("grpc-status: %d\r\n", codes.ResourceExhausted)) {
+		t.Errorf("unexpected trailers: %q", trailer)
+	}
+
+	// the limit of the server applies to the messages.
+	srv = NewServer(MaxBodySize(16))
+	srv.RegisterService(&echoServiceDesc, echoServer{})
+	in, _ := proto.Marshal(&binding.HelloRequest{Name: strings.Repeat("x", 32)})
+	req = httptest.NewRequest(http.MethodPost, "/test.Echo/Echo", bytes.NewReader(envelope(0, in)))
+	req.Header.Set("Content-Type", "application/connect+proto")
+	// a streamed body without Content-Length is read up to the limit.
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if body := w.Body.String(); !strings.Contains(body, "resource_exhausted") || !strings.Contains(body, "message exceeds 16 bytes") {
+		t.Errorf("unexpected response: %d %q", w.Code, w.Body.String())
+	}
+
+	// the decompressed unary message is limited without a body limit.
+	srv = newRPCServer(&operations)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write([]byte(`{"name":"` + strings.Repeat("x", defaultMaxMessageSize) + `"}`))
+	_ = zw.Close()
+	req = httptest.NewRequest(http.MethodPost, "/test.Echo/Echo", &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), "resource_exhausted") {
+		t.Errorf("unexpected response: %d %q", w.Code, w.Body.String())
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/go-kratos/kratos/v2/internal/endpoint"
@@ -18,6 +19,9 @@ import (
 	"github.com/go-kratos/kratos/v2/transport"
 
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 var (
@@ -114,6 +118,14 @@ func Listener(lis net.Listener) ServerOption {
 	}
 }
 
+// H2C with HTTP/2 cleartext support, which lets the clients like service mesh
+// sidecars talk HTTP/2 without TLS. It has no effect when TLS is configured.
+func H2C() ServerOption {
+	return func(s *Server) {
+		s.h2c = true
+	}
+}
+
 // Server is an HTTP server wrapper.
 type Server struct {
 	*http.Server
@@ -130,7 +142,16 @@ type Server struct {
 	enc         EncodeResponseFunc
 	ene         EncodeErrorFunc
 	strictSlash bool
+	h2c         bool
+	etag        bool
 	router      *mux.Router
+	upgrader    *websocket.Upgrader
+	wsConns     wsConnSet
+	routesMu    sync.RWMutex
+	routes      map[*mux.Route]routeConfig
+
+	maxBodySize            int64
+	contentTypeMaxBodySize map[string]int64
 }
 
 // NewServer creates an HTTP server by options.
@@ -144,16 +165,24 @@ func NewServer(opts ...ServerOption) *Server {
 		enc:         DefaultResponseEncoder,
 		ene:         DefaultErrorEncoder,
 		strictSlash: true,
+		routes:      make(map[*mux.Route]routeConfig),
 	}
 	for _, o := range opts {
 		o(srv)
 	}
+	if srv.etag {
+		srv.enc = etagEncoder(srv.enc)
+	}
 	srv.router = mux.NewRouter().StrictSlash(srv.strictSlash)
 	srv.router.NotFoundHandler = http.DefaultServeMux
-	srv.router.MethodNotAllowedHandler = http.DefaultServeMux
+	srv.router.MethodNotAllowedHandler = srv.methodNotAllowed(http.DefaultServeMux)
 	srv.router.Use(srv.filter())
+	handler := FilterChain(srv.filters...)(srv.router)
+	if srv.h2c && srv.tlsConf == nil {
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
 	srv.Server = &http.Server{
-		Handler:   FilterChain(srv.filters...)(srv.router),
+		Handler:   handler,
 		TLSConfig: srv.tlsConf,
 	}
 	return srv
@@ -213,6 +242,33 @@ func (s *Server) HandleHeader(key, val string, h http.HandlerFunc) {
 	s.router.Headers(key, val).Handler(h)
 }
 
+// WrapHandler returns a handler which runs the matched service middleware around h,
+// the operation is the path template of the route, and the error returned by
+// the middleware is encoded by the EncodeErrorFunc unless h has served the request.
+// e.g. s.Handle("/metrics", s.WrapHandler(promhttp.Handler()))
+func (s *Server) WrapHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		operation := req.URL.Path
+		if tr, ok := transport.FromServerContext(req.Context()); ok {
+			operation = tr.Operation()
+		}
+		ms := s.middleware.Match(operation)
+		if len(ms) == 0 {
+			h.ServeHTTP(w, req)
+			return
+		}
+		served := false
+		next := func(ctx context.Context, _ interface{}) (interface{}, error) {
+			h.ServeHTTP(w, req.WithContext(ctx))
+			served = true
+			return nil, nil
+		}
+		if _, err := middleware.Chain(ms...)(next)(req.Context(), nil); err != nil && !served {
+			s.ene(w, req, err)
+		}
+	})
+}
+
 // ServeHTTP should write reply headers and data to the ResponseWriter and then return.
 func (s *Server) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	s.Handler.ServeHTTP(res, req)
@@ -221,12 +277,20 @@ func (s *Server) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 func (s *Server) filter() mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			rc := s.routeConfig(req)
+			if !s.limitBody(w, req, rc) {
+				return
+			}
 			var (
-				ctx    context.Context
-				cancel context.CancelFunc
+				ctx     context.Context
+				cancel  context.CancelFunc
+				timeout = s.timeout
 			)
-			if s.timeout > 0 {
-				ctx, cancel = context.WithTimeout(req.Context(), s.timeout)
+			if rc.timeout != 0 {
+				timeout = rc.timeout
+			}
+			if timeout > 0 {
+				ctx, cancel = context.WithTimeout(req.Context(), timeout)
 			} else {
 				ctx, cancel = context.WithCancel(req.Context())
 			}
@@ -244,10 +308,12 @@ func (s *Server) filter() mux.MiddlewareFunc {
 				reqHeader:    headerCarrier(req.Header),
 				replyHeader:  headerCarrier(w.Header()),
 				request:      req,
+				peer:         &transport.Peer{Addr: req.RemoteAddr, TLS: req.TLS},
 			}
 			if s.endpoint != nil {
 				tr.endpoint = s.endpoint.String()
 			}
+			setRouteInfo(req.Context(), tr)
 			tr.request = req.WithContext(transport.NewServerContext(ctx, tr))
 			next.ServeHTTP(w, tr.request)
 		})
@@ -287,10 +353,18 @@ func (s *Server) Start(ctx context.Context) error {
 	return nil
 }
 
+// Attach prepares the server to be served by ServeHTTP on the listener of another
+// server, e.g. transport/mux, instead of Start, the endpoint is the one of the listener.
+func (s *Server) Attach(lis net.Listener, endpoint *url.URL) {
+	s.lis, s.endpoint = lis, endpoint
+}
+
 // Stop stop the HTTP server.
 func (s *Server) Stop(ctx context.Context) error {
 	log.Info("[HTTP] server stopping")
-	return s.Shutdown(ctx)
+	err := s.Shutdown(ctx)
+	s.wsConns.closeAll(ctx)
+	return err
 }
 
 func (s *Server) listenAndEndpoint() error {
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"strings"
 	"testing"
@@ -16,6 +17,9 @@ import (
 	"github.com/go-kratos/kratos/v2/errors"
 
 	"github.com/go-kratos/kratos/v2/internal/host"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/recovery"
+	"github.com/go-kratos/kratos/v2/transport"
 )
 
 var h = func(w http.ResponseWriter, r *http.Request) {
@@ -371,3 +375,110 @@ func TestListener(t *testing.T) {
 		t.Errorf("expected not empty")
 	}
 }
+
+func TestH2C(t *testing.T) {
+	srv := NewServer(H2C(), Address("127.0.0.1:0"))
+	srv.Route("/").GET("/proto", func(ctx Context) error {
+		return ctx.String(http.StatusOK, ctx.Request().Proto)
+	})
+	e, err := srv.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		if err := srv.Start(context.Background()); err != nil {
+			panic(err)
+		}
+	}()
+	defer func() { _ = srv.Stop(context.Background()) }()
+	time.Sleep(100 * time.Millisecond)
+
+	client, err := NewClient(context.Background(), WithEndpoint(e.Host), WithH2C())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "http://"+e.Host+"/proto", nil)
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != "HTTP/2.0" {
+		t.Errorf("expected HTTP/2.0, got %q", body)
+	}
+
+	// the dial is canceled with the client.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client, err = NewClient(ctx, WithEndpoint(e.Host), WithH2C())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.Do(req); err == nil {
+		t.Error("expected an error of the canceled client")
+	}
+	if _, err = NewClient(context.Background(), WithH2C(), WithTransport(http.DefaultTransport)); err == nil {
+		t.Error("expected an error of WithH2C with WithTransport")
+	}
+}
+
+func TestWrapHandler(t *testing.T) {
+	var operations []string
+	srv := NewServer(Middleware(recovery.Recovery(), func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, _ := transport.FromServerContext(ctx)
+			operations = append(operations, tr.Operation())
+			if tr.RequestHeader().Get("Authorization") == "" {
+				return nil, errors.Unauthorized("UNAUTHORIZED", "missing token")
+			}
+			return handler(ctx, req)
+		}
+	}))
+	srv.Handle("/raw/{id}", srv.WrapHandler(http.HandlerFunc(h)))
+	srv.HandlePrefix("/static/", srv.WrapHandler(http.HandlerFunc(h)))
+	srv.HandleFunc("/panic", srv.WrapHandler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("raw handler")
+	})).ServeHTTP)
+
+	tests := []struct {
+		path  string
+		token string
+		code  int
+	}{
+		{"/raw/1", "token", http.StatusOK},
+		{"/raw/2", "", http.StatusUnauthorized},
+		{"/static/index.html", "token", http.StatusOK},
+		{"/panic", "token", http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.token != "" {
+			req.Header.Set("Authorization", test.token)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s: expected %d, got %d", test.path, test.code, w.Code)
+		}
+	}
+	want := []string{"/raw/{id}", "/raw/{id}", "/static/", "/panic"}
+	if !reflect.DeepEqual(operations, want) {
+		t.Errorf("expected %v, got %v", want, operations)
+	}
+}
+
+func TestServerPeer(t *testing.T) {
+	var peer *transport.Peer
+	srv := NewServer()
+	srv.HandleFunc("/peer", func(w http.ResponseWriter, r *http.Request) {
+		peer, _ = transport.PeerFromServerContext(r.Context())
+	})
+	req := httptest.NewRequest(http.MethodGet, "/peer", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.TLS = &tls.ConnectionState{}
+	srv.ServeHTTP(httptest.NewRecorder(), req)
+	if peer == nil || peer.Addr != req.RemoteAddr || peer.TLS != req.TLS {
+		t.Errorf("unexpected peer: %+v", peer)
+	}
+}
@@ -0,0 +1,320 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+)
+
+var (
+	// ErrStreamUnsupported is returned when the response writer does not support flushing.
+	ErrStreamUnsupported = errors.New("http: response writer does not implement http.Flusher")
+	// ErrStreamClosed is returned when writing to a closed event stream.
+	ErrStreamClosed = errors.New("http: event stream is closed")
+)
+
+// Event is a Server-Sent Event.
+type Event struct {
+	// ID is the event id, which is sent back by the browser in the Last-Event-ID header when reconnecting.
+	ID string
+	// Event is the event name, the browser dispatches unnamed events as "message".
+	Event string
+	// Data is the event data, multiple lines are sent as multiple data fields.
+	Data []byte
+	// Retry is the reconnection time of the browser.
+	Retry time.Duration
+}
+
+// EventStream is a Server-Sent Events stream of the response.
+// The response headers are written on the first event, and the stream
+// must be closed before the handler returns.
+type EventStream struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	req     *http.Request
+	codec   encoding.Codec
+	started bool
+	closed  bool
+	stop    chan struct{}
+}
+
+// SSE returns the Server-Sent Events stream of the response of ctx.
+func SSE(ctx Context) *EventStream {
+	return newEventStream(ctx.Response(), ctx.Request())
+}
+
+func newEventStream(w http.ResponseWriter, req *http.Request) *EventStream {
+	return &EventStream{
+		w:     w,
+		req:   req,
+		codec: encoding.GetCodec("json"),
+		stop:  make(chan struct{}),
+	}
+}
+
+// LastEventID returns the Last-Event-ID header sent by the browser when reconnecting.
+func (s *EventStream) LastEventID() string {
+	return s.req.Header.Get("Last-Event-ID")
+}
+
+// start writes the response headers, the caller must hold the lock.
+func (s *EventStream) start() error {
+	if s.started {
+		return nil
+	}
+	if _, ok := s.w.(http.Flusher); !ok {
+		return ErrStreamUnsupported
+	}
+	header := s.w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// disable the response buffering of nginx
+	header.Set("X-Accel-Buffering", "no")
+	s.w.WriteHeader(http.StatusOK)
+	s.started = true
+	return nil
+}
+
+func (s *EventStream) write(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStreamClosed
+	}
+	if err := s.start(); err != nil {
+		return err
+	}
+	if _, err := s.w.Write(data); err != nil {
+		return err
+	}
+	s.w.(http.Flusher).Flush()
+	return nil
+}
+
+// Send writes the event to the stream and flushes it.
+func (s *EventStream) Send(e *Event) error {
+	buf := bytes.NewBuffer(nil)
+	if e.ID != "" {
+		buf.WriteString("id: " + sanitizeEventField(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		buf.WriteString("event: " + sanitizeEventField(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(string(e.Data), "\r\n", "\n"), "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteByte('\n')
+	return s.write(buf.Bytes())
+}
+
+// Encode writes v encoded with the json codec as the data of an unnamed event.
+func (s *EventStream) Encode(v interface{}) error {
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.Send(&Event{Data: data})
+}
+
+// Comment writes a comment line to the stream, which is ignored by the browser.
+func (s *EventStream) Comment(text string) error {
+	return s.write([]byte(": " + sanitizeEventField(text) + "\n\n"))
+}
+
+// KeepAlive writes a keep-alive comment at every interval until the stream is closed,
+// so that proxies do not close the idle connection.
+func (s *EventStream) KeepAlive(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Comment("keep-alive"); err != nil {
+					return
+				}
+			case <-s.stop:
+				return
+			case <-s.req.Context().Done():
+				return
+			}
+		}
+	}()
+}
+
+// Close closes the stream. If err is not nil and the stream has started, the error
+// is sent as an "error" event and nil is returned, otherwise err is returned to be
+// encoded as an HTTP response.
+func (s *EventStream) Close(err error) error {
+	s.mu.Lock()
+	started := s.started
+	s.mu.Unlock()
+	if err != nil && started {
+		if data, e := s.codec.Marshal(kerrors.FromError(err)); e == nil {
+			_ = s.Send(&Event{Event: "error", Data: data})
+		}
+		err = nil
+	}
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.stop)
+	}
+	s.mu.Unlock()
+	return err
+}
+
+// ServerStream returns a grpc.ServerStream which sends messages as events, it allows
+// server-streaming gRPC methods to be served as Server-Sent Events.
+func (s *EventStream) ServerStream(ctx context.Context) grpc.ServerStream {
+	return &eventServerStream{ctx: ctx, stream: s}
+}
+
+func sanitizeEventField(v string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(v)
+}
+
+type eventServerStream struct {
+	ctx    context.Context
+	stream *EventStream
+}
+
+func (s *eventServerStream) SetHeader(md metadata.MD) error {
+	s.stream.mu.Lock()
+	defer s.stream.mu.Unlock()
+	if s.stream.started {
+		return errors.New("http: the headers have been sent")
+	}
+	for k, vs := range md {
+		for _, v := range vs {
+			s.stream.w.Header().Add(k, v)
+		}
+	}
+	return nil
+}
+
+func (s *eventServerStream) SendHeader(md metadata.MD) error {
+	if err := s.SetHeader(md); err != nil {
+		return err
+	}
+	s.stream.mu.Lock()
+	defer s.stream.mu.Unlock()
+	if err := s.stream.start(); err != nil {
+		return err
+	}
+	s.stream.w.(http.Flusher).Flush()
+	return nil
+}
+
+func (s *eventServerStream) SetTrailer(metadata.MD) {}
+
+func (s *eventServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *eventServerStream) SendMsg(m interface{}) error {
+	return s.stream.Encode(m)
+}
+
+func (s *eventServerStream) RecvMsg(interface{}) error {
+	return errors.New("http: receiving messages is not supported by Server-Sent Events")
+}
+
+// EventReader reads Server-Sent Events from a response body.
+type EventReader struct {
+	r     *bufio.Reader
+	codec encoding.Codec
+	id    string
+}
+
+// NewEventReader returns a reader of the events from r.
+func NewEventReader(r io.Reader) *EventReader {
+	return &EventReader{
+		r:     bufio.NewReader(r),
+		codec: encoding.GetCodec("json"),
+	}
+}
+
+// Next returns the next event of the stream, comments are skipped and the
+// last event id is kept for the following events. It returns io.EOF at the
+// end of the stream, the incomplete event at the end is discarded.
+func (r *EventReader) Next() (*Event, error) {
+	var (
+		e    = &Event{}
+		data [][]byte
+	)
+	for {
+		line, err := r.r.ReadBytes('\n')
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) == 0 {
+			if data == nil {
+				e.Event = ""
+				continue
+			}
+			e.ID = r.id
+			e.Data = bytes.Join(data, []byte("\n"))
+			return e, nil
+		}
+		if line[0] == ':' {
+			continue
+		}
+		field, value := line, []byte(nil)
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], bytes.TrimPrefix(line[i+1:], []byte(" "))
+		}
+		switch string(field) {
+		case "id":
+			if bytes.IndexByte(value, 0) < 0 {
+				r.id = string(value)
+			}
+		case "event":
+			e.Event = string(value)
+		case "data":
+			data = append(data, value)
+		case "retry":
+			if ms, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+				e.Retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+// Decode decodes the data of the next event with the json codec into v.
+// An "error" event sent by EventStream.Close is returned as an *errors.Error.
+func (r *EventReader) Decode(v interface{}) error {
+	e, err := r.Next()
+	if err != nil {
+		return err
+	}
+	if e.Event == "error" {
+		se := new(kerrors.Error)
+		if err = r.codec.Unmarshal(e.Data, se); err != nil {
+			return err
+		}
+		return se
+	}
+	return r.codec.Unmarshal(e.Data, v)
+}
@@ -0,0 +1,131 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+)
+
+func TestEventStream(t *testing.T) {
+	res := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	stream := newEventStream(res, req)
+	if stream.LastEventID() != "1" {
+		t.Errorf("expected last event id 1 got %s", stream.LastEventID())
+	}
+	if err := stream.Send(&Event{ID: "2", Event: "update", Data: []byte("line1\nline2"), Retry: time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Comment("ping"); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Encode(map[string]string{"k": "v"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Close(kerrors.BadRequest("REASON", "message")); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Comment("closed"); !errors.Is(err, ErrStreamClosed) {
+		t.Errorf("expected ErrStreamClosed got %v", err)
+	}
+	if res.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("unexpected content type %s", res.Header().Get("Content-Type"))
+	}
+	want := "id: 2\nevent: update\nretry: 1000\ndata: line1\ndata: line2\n\n" +
+		": ping\n\n" +
+		"data: {\"k\":\"v\"}\n\n" +
+		"event: error\ndata: {"
+	// the error is encoded by protojson, whose output whitespace is unstable.
+	if got := res.Body.String(); !strings.HasPrefix(got, want) || !strings.Contains(got, "REASON") {
+		t.Errorf("expected %q got %q", want, got)
+	}
+}
+
+func TestEventStreamNotStarted(t *testing.T) {
+	stream := newEventStream(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil))
+	err := errors.New("failed")
+	if got := stream.Close(err); got != err {
+		t.Errorf("expected error returned before the stream started, got %v", got)
+	}
+}
+
+func TestContextSSE(t *testing.T) {
+	srv := NewServer()
+	srv.Route("/").GET("/events", func(ctx Context) error {
+		stream := SSE(ctx)
+		stream.KeepAlive(time.Millisecond)
+		time.Sleep(10 * time.Millisecond)
+		return stream.Close(stream.Send(&Event{Data: []byte("hello")}))
+	})
+	res := httptest.NewRecorder()
+	srv.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if res.Code != http.StatusOK || res.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("unexpected response %d %v", res.Code, res.Header())
+	}
+}
+
+func TestEventStreamTimeout(t *testing.T) {
+	srv := NewServer(Timeout(time.Second))
+	deadline := func(ctx Context) error {
+		_, ok := ctx.Deadline()
+		return ctx.String(200, fmt.Sprint(ok))
+	}
+	r := srv.Route("/")
+	r.GET("/unary", deadline)
+	r.Timeout(-1).GET("/events", deadline)
+	tests := map[string]string{
+		"/unary":  "true",
+		"/events": "false",
+	}
+	for path, want := range tests {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		// the Accept header of the client doesn't remove the timeout of the route.
+		req.Header.Set("Accept", "text/event-stream")
+		srv.ServeHTTP(res, req)
+		if got := res.Body.String(); got != want {
+			t.Errorf("%s: expected deadline %s got %s", path, want, got)
+		}
+	}
+}
+
+func TestEventReader(t *testing.T) {
+	res := httptest.NewRecorder()
+	stream := newEventStream(res, httptest.NewRequest(http.MethodGet, "/events", nil))
+	_ = stream.Send(&Event{ID: "1", Event: "update", Data: []byte("line1\nline2"), Retry: time.Second})
+	_ = stream.Comment("ping")
+	_ = stream.Encode(map[string]string{"k": "v"})
+	_ = stream.Close(kerrors.NotFound("NOT_FOUND", "not found"))
+
+	r := NewEventReader(strings.NewReader(strings.ReplaceAll(res.Body.String(), "\n\n", "\r\n\r\n")))
+	e, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ID != "1" || e.Event != "update" || string(e.Data) != "line1\nline2" || e.Retry != time.Second {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	var v map[string]string
+	if err = r.Decode(&v); err != nil || v["k"] != "v" {
+		t.Errorf("unexpected value: %v %v", v, err)
+	}
+	if err = r.Decode(&v); !kerrors.IsNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+	if _, err = r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+
+	r = NewEventReader(strings.NewReader("data: incomplete"))
+	if _, err = r.Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
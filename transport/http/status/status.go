@@ -43,7 +43,7 @@ func (c statusConverter) ToGRPCCode(code int) codes.Code {
 		return codes.NotFound
 	case http.StatusConflict:
 		return codes.Aborted
-	case http.StatusTooManyRequests:
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
 		return codes.ResourceExhausted
 	case http.StatusInternalServerError:
 		return codes.Internal
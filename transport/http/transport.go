@@ -7,7 +7,10 @@ import (
 	"github.com/go-kratos/kratos/v2/transport"
 )
 
-var _ Transporter = &Transport{}
+var (
+	_ Transporter            = &Transport{}
+	_ transport.PeerProvider = &Transport{}
+)
 
 // Transporter is http Transporter
 type Transporter interface {
@@ -24,6 +27,7 @@ type Transport struct {
 	replyHeader  headerCarrier
 	request      *http.Request
 	pathTemplate string
+	peer         *transport.Peer
 }
 
 // Kind returns the transport kind.
@@ -56,6 +60,11 @@ func (tr *Transport) ReplyHeader() transport.Header {
 	return tr.replyHeader
 }
 
+// Peer returns the remote peer of the server transport, nil for the client transport.
+func (tr *Transport) Peer() *transport.Peer {
+	return tr.peer
+}
+
 // PathTemplate returns the http path template.
 func (tr *Transport) PathTemplate() string {
 	return tr.pathTemplate
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// WebSocketHandler defines a function to serve WebSocket connections,
+// the ctx is the one returned by the middleware chain at upgrade time.
+type WebSocketHandler func(ctx context.Context, conn *WebSocketConn) error
+
+// WebSocketUpgrader with the WebSocket upgrader, e.g. to check the origin or
+// negotiate other subprotocols.
+func WebSocketUpgrader(u *websocket.Upgrader) ServerOption {
+	return func(o *Server) {
+		o.upgrader = u
+	}
+}
+
+// WebSocket registers a new WebSocket route for a path with matching handler in the router.
+// The server middleware are run before the connection is upgraded, an error returned by
+// the middleware is encoded as an HTTP response and the connection is not upgraded.
+// The connections are long-lived, so the route has no timeout.
+func (r *Router) WebSocket(relativePath string, h WebSocketHandler, filters ...FilterFunc) {
+	r.Timeout(-1).Handle(http.MethodGet, relativePath, func(ctx Context) error {
+		var upgradeCtx context.Context
+		m := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			upgradeCtx = ctx
+			return nil, nil
+		})
+		if _, err := m(ctx, ctx.Request()); err != nil {
+			return err
+		}
+		conn, err := r.srv.upgradeWebSocket(ctx.Response(), ctx.Request())
+		if err != nil {
+			// the upgrader replies to the client with an HTTP error.
+			return nil
+		}
+		defer r.srv.wsConns.remove(conn)
+		if err := h(upgradeCtx, conn); err != nil {
+			conn.closeWithError(err)
+		}
+		return nil
+	}, filters...)
+}
+
+func (s *Server) upgradeWebSocket(w http.ResponseWriter, req *http.Request) (*WebSocketConn, error) {
+	upgrader := s.upgrader
+	if upgrader == nil {
+		upgrader = &websocket.Upgrader{Subprotocols: []string{"json", "proto"}}
+	}
+	conn, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		return nil, err
+	}
+	c := newWebSocketConn(conn)
+	if !s.wsConns.add(c) {
+		c.closeWithCode(websocket.CloseGoingAway, "server shutdown", time.Now().Add(time.Second))
+		return nil, http.ErrServerClosed
+	}
+	return c, nil
+}
+
+// WebSocketConn is a WebSocket connection which sends and receives messages
+// with the codec negotiated by the subprotocol, default is json.
+type WebSocketConn struct {
+	*websocket.Conn
+	codec       encoding.Codec
+	messageType int
+}
+
+func newWebSocketConn(conn *websocket.Conn) *WebSocketConn {
+	codec := encoding.GetCodec(conn.Subprotocol())
+	if codec == nil {
+		codec = encoding.GetCodec("json")
+	}
+	messageType := websocket.TextMessage
+	if codec.Name() == "proto" {
+		messageType = websocket.BinaryMessage
+	}
+	return &WebSocketConn{Conn: conn, codec: codec, messageType: messageType}
+}
+
+// Codec returns the codec of the connection.
+func (c *WebSocketConn) Codec() encoding.Codec {
+	return c.codec
+}
+
+// Send encodes v with the codec and writes it as a message.
+func (c *WebSocketConn) Send(v interface{}) error {
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(c.messageType, data)
+}
+
+// Receive reads a message and decodes it into v with the codec.
+func (c *WebSocketConn) Receive(v interface{}) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return c.codec.Unmarshal(data, v)
+}
+
+func (c *WebSocketConn) closeWithError(err error) {
+	if _, ok := err.(*websocket.CloseError); ok {
+		_ = c.Close()
+		return
+	}
+	se := errors.FromError(err)
+	reason := se.Reason
+	if reason == "" {
+		reason = se.Message
+	}
+	c.closeWithCode(websocket.CloseInternalServerErr, reason, time.Now().Add(time.Second))
+}
+
+func (c *WebSocketConn) closeWithCode(code int, text string, deadline time.Time) {
+	// the close reason of a control frame must not exceed 123 bytes.
+	if len(text) > 123 {
+		text = text[:123]
+	}
+	_ = c.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), deadline)
+	_ = c.Close()
+}
+
+// wsConnSet tracks the active WebSocket connections of the server,
+// which are closed with a going away frame when the server stops.
+type wsConnSet struct {
+	mu     sync.Mutex
+	conns  map[*WebSocketConn]struct{}
+	closed bool
+}
+
+func (s *wsConnSet) add(c *WebSocketConn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = make(map[*WebSocketConn]struct{})
+	}
+	s.conns[c] = struct{}{}
+	return true
+}
+
+func (s *wsConnSet) remove(c *WebSocketConn) {
+	s.mu.Lock()
+	delete(s.conns, c)
+	s.mu.Unlock()
+	_ = c.Close()
+}
+
+func (s *wsConnSet) closeAll(ctx context.Context) {
+	s.mu.Lock()
+	s.closed = true
+	conns := s.conns
+	s.conns = nil
+	s.mu.Unlock()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(time.Second)
+	}
+	for c := range conns {
+		c.closeWithCode(websocket.CloseGoingAway, "server shutdown", deadline)
+	}
+}
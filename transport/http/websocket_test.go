@@ -0,0 +1,142 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+type wsMessage struct {
+	Text string `json:"text"`
+}
+
+func TestWebSocket(t *testing.T) {
+	type operationKey struct{}
+	srv := NewServer(Middleware(func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, _ := transport.FromServerContext(ctx)
+			if tr.RequestHeader().Get("Authorization") == "" {
+				return nil, errors.Unauthorized("UNAUTHORIZED", "token is missing")
+			}
+			return handler(context.WithValue(ctx, operationKey{}, tr.Operation()), req)
+		}
+	}))
+	done := make(chan struct{})
+	srv.Route("/").WebSocket("/chat/{room}", func(ctx context.Context, conn *WebSocketConn) error {
+		defer close(done)
+		if op := ctx.Value(operationKey{}); op != "/chat/{room}" {
+			t.Errorf("expected operation /chat/{room} got %v", op)
+		}
+		for {
+			var msg wsMessage
+			if err := conn.Receive(&msg); err != nil {
+				return err
+			}
+			if err := conn.Send(&wsMessage{Text: "echo: " + msg.Text}); err != nil {
+				return err
+			}
+		}
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/chat/kratos"
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized, got %v", err)
+	}
+
+	header := http.Header{"Authorization": []string{"Bearer token"}}
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err = conn.WriteJSON(&wsMessage{Text: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	var reply wsMessage
+	if err = conn.ReadJSON(&reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Text != "echo: hello" {
+		t.Errorf("expected echo: hello got %s", reply.Text)
+	}
+
+	if err = srv.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("expected going away close error, got %v", err)
+	}
+	<-done
+}
+
+func TestWebSocketSubprotocol(t *testing.T) {
+	srv := NewServer()
+	srv.Route("/").WebSocket("/ws", func(ctx context.Context, conn *WebSocketConn) error {
+		if conn.Codec().Name() != "proto" {
+			t.Errorf("expected proto codec got %s", conn.Codec().Name())
+		}
+		return nil
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	dialer := &websocket.Dialer{Subprotocols: []string{"proto"}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if conn.Subprotocol() != "proto" {
+		t.Errorf("expected proto subprotocol got %s", conn.Subprotocol())
+	}
+}
+
+func TestWebSocketTimeout(t *testing.T) {
+	srv := NewServer(Timeout(time.Second))
+	r := srv.Route("/")
+	r.GET("/unary", func(ctx Context) error {
+		_, ok := ctx.Deadline()
+		return ctx.String(http.StatusOK, fmt.Sprint(ok))
+	})
+	deadline := make(chan bool, 1)
+	r.WebSocket("/ws", func(ctx context.Context, conn *WebSocketConn) error {
+		_, ok := ctx.Deadline()
+		deadline <- ok
+		return nil
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	// the upgrade headers of the client don't remove the timeout of the route.
+	req := httptest.NewRequest(http.MethodGet, "/unary", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	res := httptest.NewRecorder()
+	srv.ServeHTTP(res, req)
+	if res.Body.String() != "true" {
+		t.Errorf("expected the deadline of the route, got %s", res.Body.String())
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if <-deadline {
+		t.Error("expected no deadline of the WebSocket route")
+	}
+}
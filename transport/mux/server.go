@@ -0,0 +1,222 @@
+package mux
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/go-kratos/kratos/v2/internal/endpoint"
+	"github.com/go-kratos/kratos/v2/internal/host"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/grpc"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+)
+
+var (
+	_ transport.Server          = (*Server)(nil)
+	_ transport.Endpointer      = (*Server)(nil)
+	_ transport.MultiEndpointer = (*Server)(nil)
+	_ http.Handler              = (*Server)(nil)
+)
+
+// ServerOption is a multiplexing server option.
+type ServerOption func(*Server)
+
+// Network with server network.
+func Network(network string) ServerOption {
+	return func(s *Server) {
+		s.network = network
+	}
+}
+
+// Address with server address.
+func Address(addr string) ServerOption {
+	return func(s *Server) {
+		s.address = addr
+	}
+}
+
+// TLSConfig with TLS config.
+func TLSConfig(c *tls.Config) ServerOption {
+	return func(s *Server) {
+		s.tlsConf = c
+	}
+}
+
+// Listener with server lis
+func Listener(lis net.Listener) ServerOption {
+	return func(s *Server) {
+		s.lis = lis
+	}
+}
+
+// Server serves a gRPC server and an HTTP server on a single listener. The gRPC
+// requests are sniffed by the HTTP/2 application/grpc content type, and HTTP/2
+// is served over TLS or in cleartext (h2c).
+type Server struct {
+	*http.Server
+	http     *khttp.Server
+	grpc     *grpc.Server
+	lis      net.Listener
+	tlsConf  *tls.Config
+	network  string
+	address  string
+	err      error
+	endpoint *url.URL
+	// the number of the in-flight gRPC requests
+	streams int64
+}
+
+// NewServer creates a server which multiplexes the HTTP and gRPC servers.
+// The listener options of the HTTP and gRPC servers are not used.
+func NewServer(hs *khttp.Server, gs *grpc.Server, opts ...ServerOption) *Server {
+	srv := &Server{
+		http:    hs,
+		grpc:    gs,
+		network: "tcp",
+		address: ":0",
+	}
+	for _, o := range opts {
+		o(srv)
+	}
+	handler := http.Handler(srv)
+	if srv.tlsConf == nil {
+		handler = h2c.NewHandler(srv, &http2.Server{})
+	}
+	srv.Server = &http.Server{
+		Handler:   handler,
+		TLSConfig: srv.tlsConf,
+	}
+	return srv
+}
+
+// ServeHTTP dispatches the gRPC requests to the gRPC server and the others to the HTTP server.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if isGRPC(req) {
+		atomic.AddInt64(&s.streams, 1)
+		defer atomic.AddInt64(&s.streams, -1)
+		s.grpc.ServeHTTP(w, req)
+		return
+	}
+	s.http.ServeHTTP(w, req)
+}
+
+// Endpoint return the HTTP endpoint of the server.
+// examples:
+//
+//	http://127.0.0.1:8000?isSecure=false
+func (s *Server) Endpoint() (*url.URL, error) {
+	if err := s.listenAndEndpoint(); err != nil {
+		return nil, err
+	}
+	return s.endpoint, nil
+}
+
+// Endpoints return the HTTP and gRPC endpoints of the server, which share the address.
+func (s *Server) Endpoints() ([]*url.URL, error) {
+	if err := s.listenAndEndpoint(); err != nil {
+		return nil, err
+	}
+	return []*url.URL{s.endpoint, s.grpcEndpoint()}, nil
+}
+
+func (s *Server) grpcEndpoint() *url.URL {
+	return endpoint.NewEndpoint(endpoint.Scheme("grpc", s.tlsConf != nil), s.endpoint.Host)
+}
+
+// Start start the multiplexing server, the HTTP and gRPC servers are attached to
+// its listener instead of being started, see grpc.Server.Attach.
+func (s *Server) Start(ctx context.Context) error {
+	if err := s.listenAndEndpoint(); err != nil {
+		return err
+	}
+	s.http.Attach(s.lis, s.endpoint)
+	s.grpc.Attach(ctx, s.lis, s.grpcEndpoint())
+	s.BaseContext = func(net.Listener) context.Context {
+		return ctx
+	}
+	log.Infof("[HTTP/gRPC] server listening on: %s", s.lis.Addr().String())
+	var err error
+	if s.tlsConf != nil {
+		err = s.ServeTLS(s.lis, "", "")
+	} else {
+		err = s.Serve(s.lis)
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Stop stop the multiplexing server, it waits for the active requests until the
+// ctx is done, then the remaining gRPC streams are closed.
+func (s *Server) Stop(ctx context.Context) error {
+	log.Info("[HTTP/gRPC] server stopping")
+	// closes the WebSocket connections, which are not tracked by Shutdown.
+	_ = s.http.Stop(ctx)
+	err := s.Shutdown(ctx)
+	if err == nil {
+		// the h2c connections are hijacked, which are not tracked by Shutdown.
+		err = s.waitStreams(ctx)
+	}
+	if err != nil {
+		// closes the remaining streams, since the graceful stop of the gRPC server
+		// can't drain the streams served by ServeHTTP.
+		s.grpc.Server.Stop()
+		_ = s.Close()
+	}
+	_ = s.grpc.Stop(ctx)
+	return err
+}
+
+// waitStreams waits for the in-flight gRPC requests until the ctx is done.
+func (s *Server) waitStreams(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for atomic.LoadInt64(&s.streams) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+func (s *Server) listenAndEndpoint() error {
+	if s.lis == nil {
+		lis, err := net.Listen(s.network, s.address)
+		if err != nil {
+			s.err = err
+			return err
+		}
+		s.lis = lis
+	}
+	if s.endpoint == nil {
+		addr, err := host.Extract(s.address, s.lis)
+		if err != nil {
+			s.err = err
+			return err
+		}
+		s.endpoint = endpoint.NewEndpoint(endpoint.Scheme("http", s.tlsConf != nil), addr)
+	}
+	return s.err
+}
+
+func isGRPC(req *http.Request) bool {
+	if req.ProtoMajor != 2 {
+		return false
+	}
+	contentType := req.Header.Get("Content-Type")
+	return strings.HasPrefix(contentType, "application/grpc") && !strings.HasPrefix(contentType, "application/grpc-web")
+}
@@ -0,0 +1,166 @@
+package mux
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	pb "github.com/go-kratos/kratos/v2/internal/testdata/helloworld"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport/grpc"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+)
+
+type server struct {
+	pb.UnimplementedGreeterServer
+}
+
+func (s *server) SayHello(ctx context.Context, in *pb.HelloRequest) (*pb.HelloReply, error) {
+	return &pb.HelloReply{Message: "hello " + in.Name}, nil
+}
+
+func (s *server) SayHelloStream(stream pb.Greeter_SayHelloStreamServer) error {
+	in, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if in.Name == "hang" {
+		<-stream.Context().Done()
+		return stream.Context().Err()
+	}
+	time.Sleep(100 * time.Millisecond)
+	return stream.Send(&pb.HelloReply{Message: "hello " + in.Name})
+}
+
+func TestServer(t *testing.T) {
+	hs := khttp.NewServer()
+	hs.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("pong"))
+	})
+	type baseKey struct{}
+	var base interface{}
+	gs := grpc.NewServer(grpc.Middleware(func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			base = ctx.Value(baseKey{})
+			return handler(ctx, req)
+		}
+	}))
+	pb.RegisterGreeterServer(gs, &server{})
+	srv := NewServer(hs, gs, Address("127.0.0.1:0"))
+
+	endpoints, err := srv.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(endpoints) != 2 || endpoints[0].Scheme != "http" || endpoints[1].Scheme != "grpc" || endpoints[0].Host != endpoints[1].Host {
+		t.Fatalf("unexpected endpoints: %v", endpoints)
+	}
+	go func() {
+		if err := srv.Start(context.WithValue(context.Background(), baseKey{}, "app")); err != nil {
+			t.Error(err)
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// the HTTP and gRPC servers are attached to the listener of the server.
+	if u, err := hs.Endpoint(); err != nil || u.String() != endpoints[0].String() {
+		t.Errorf("unexpected HTTP endpoint: %v %v", u, err)
+	}
+	if u, err := gs.Endpoint(); err != nil || u.String() != endpoints[1].String() {
+		t.Errorf("unexpected gRPC endpoint: %v %v", u, err)
+	}
+
+	res, err := http.Get("http://" + endpoints[0].Host + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if string(body) != "pong" {
+		t.Errorf("expected pong, got %q", body)
+	}
+
+	conn, err := ggrpc.Dial(endpoints[1].Host, ggrpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	reply, err := pb.NewGreeterClient(conn).SayHello(context.Background(), &pb.HelloRequest{Name: "kratos"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Message != "hello kratos" {
+		t.Errorf("expected hello kratos, got %q", reply.Message)
+	}
+	if base != "app" {
+		t.Errorf("expected the base context of the server, got %v", base)
+	}
+	health, err := grpc_health_v1.NewHealthClient(conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil || health.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Errorf("expected the health serving, got %v %v", health, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := srv.Stop(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServerStopStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		stopped bool
+	}{
+		{"kratos", time.Second, true},
+		{"hang", 200 * time.Millisecond, false},
+	}
+	for _, test := range tests {
+		gs := grpc.NewServer()
+		pb.RegisterGreeterServer(gs, &server{})
+		srv := NewServer(khttp.NewServer(), gs, Address("127.0.0.1:0"))
+		endpoints, err := srv.Endpoints()
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			_ = srv.Start(context.Background())
+		}()
+		time.Sleep(100 * time.Millisecond)
+
+		conn, err := ggrpc.Dial(endpoints[1].Host, ggrpc.WithInsecure())
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream, err := pb.NewGreeterClient(conn).SayHelloStream(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = stream.Send(&pb.HelloRequest{Name: test.name}); err != nil {
+			t.Fatal(err)
+		}
+		// wait for the stream to be served.
+		time.Sleep(50 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), test.timeout)
+		err = srv.Stop(ctx)
+		cancel()
+		if (err == nil) != test.stopped {
+			t.Errorf("%s: unexpected error of stop: %v", test.name, err)
+		}
+		reply, err := stream.Recv()
+		if test.stopped && (err != nil || reply.Message != "hello kratos") {
+			t.Errorf("%s: expected the in-flight stream to be finished, got %v %v", test.name, reply, err)
+		}
+		if !test.stopped && err == nil {
+			t.Errorf("%s: expected the in-flight stream to be closed", test.name)
+		}
+		_ = conn.Close()
+	}
+}
@@ -0,0 +1,75 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+)
+
+// Peer is the information of the remote peer of a server transport.
+type Peer struct {
+	// Addr is the remote address of the peer.
+	Addr string
+	// TLS is the state of the TLS connection, nil without TLS.
+	TLS *tls.ConnectionState
+}
+
+// PeerProvider is the transporter which provides the peer information,
+// it is implemented by the server transports of HTTP and gRPC.
+type PeerProvider interface {
+	Peer() *Peer
+}
+
+// PeerFromServerContext returns the peer of the server transport stored in ctx, if any.
+func PeerFromServerContext(ctx context.Context) (*Peer, bool) {
+	tr, ok := FromServerContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	pp, ok := tr.(PeerProvider)
+	if !ok {
+		return nil, false
+	}
+	p := pp.Peer()
+	return p, p != nil
+}
+
+// Certificate returns the leaf certificate of the verified chain of the peer,
+// nil without mutual TLS or if the peer certificate is not verified, e.g. with
+// tls.RequestClientCert, so that the identity of the peer can't be forged.
+func (p *Peer) Certificate() *x509.Certificate {
+	if p.TLS == nil || len(p.TLS.VerifiedChains) == 0 || len(p.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return p.TLS.VerifiedChains[0][0]
+}
+
+// SPIFFEID returns the SPIFFE ID in the URI SANs of the peer certificate.
+func (p *Peer) SPIFFEID() string {
+	cert := p.Certificate()
+	if cert == nil {
+		return ""
+	}
+	for _, uri := range cert.URIs {
+		if uri.Scheme == "spiffe" {
+			return uri.String()
+		}
+	}
+	return ""
+}
+
+// Identity returns the identity of the peer certificate, which is the SPIFFE ID,
+// or the first DNS SAN, or the common name, in order of precedence.
+func (p *Peer) Identity() string {
+	if id := p.SPIFFEID(); id != "" {
+		return id
+	}
+	cert := p.Certificate()
+	if cert == nil {
+		return ""
+	}
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+	return cert.Subject.CommonName
+}
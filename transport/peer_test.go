@@ -0,0 +1,71 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+)
+
+type peerTransport struct {
+	Transporter
+	peer *Peer
+}
+
+func (tr *peerTransport) Peer() *Peer { return tr.peer }
+
+func TestPeerIdentity(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://cluster.local/ns/default/sa/greeter")
+	other, _ := url.Parse("https://example.com")
+	tests := []struct {
+		name string
+		cert *x509.Certificate
+		id   string
+	}{
+		{"spiffe", &x509.Certificate{URIs: []*url.URL{other, spiffe}, DNSNames: []string{"greeter"}}, spiffe.String()},
+		{"dns", &x509.Certificate{URIs: []*url.URL{other}, DNSNames: []string{"greeter"}}, "greeter"},
+		{"cn", &x509.Certificate{Subject: pkix.Name{CommonName: "greeter"}}, "greeter"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := &Peer{TLS: &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{test.cert},
+				VerifiedChains:   [][]*x509.Certificate{{test.cert}},
+			}}
+			if p.Certificate() != test.cert {
+				t.Errorf("unexpected certificate: %v", p.Certificate())
+			}
+			if got := p.Identity(); got != test.id {
+				t.Errorf("expected %q, got %q", test.id, got)
+			}
+		})
+	}
+	p := &Peer{Addr: "127.0.0.1:1234"}
+	if p.Certificate() != nil || p.SPIFFEID() != "" || p.Identity() != "" {
+		t.Errorf("expected no identity without TLS: %+v", p)
+	}
+	// the unverified certificate, e.g. self-signed with tls.RequestClientCert, has no identity.
+	p = &Peer{TLS: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{tests[0].cert}}}
+	if p.Certificate() != nil || p.SPIFFEID() != "" || p.Identity() != "" {
+		t.Errorf("expected no identity of the unverified certificate: %+v", p)
+	}
+}
+
+func TestPeerFromServerContext(t *testing.T) {
+	if _, ok := PeerFromServerContext(context.Background()); ok {
+		t.Error("expected no peer")
+	}
+	if _, ok := PeerFromServerContext(NewServerContext(context.Background(), &mockTransport{})); ok {
+		t.Error("expected no peer of the transport without PeerProvider")
+	}
+	if _, ok := PeerFromServerContext(NewServerContext(context.Background(), &peerTransport{})); ok {
+		t.Error("expected no peer of the nil peer")
+	}
+	want := &Peer{Addr: "127.0.0.1:1234"}
+	p, ok := PeerFromServerContext(NewServerContext(context.Background(), &peerTransport{peer: want}))
+	if !ok || p != want {
+		t.Errorf("expected %v, got %v", want, p)
+	}
+}
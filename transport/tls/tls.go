@@ -0,0 +1,317 @@
+// Package tls loads the certificates of mutual TLS from files, and reloads them
+// when the files change, so that the rotated certificates, e.g. by cert-manager,
+// take effect without restarting the HTTP and gRPC transports.
+package tls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// Option is a Loader option.
+type Option func(*options)
+
+type options struct {
+	certFile   string
+	keyFile    string
+	caFile     string
+	peerNames  []string
+	clientAuth tls.ClientAuthType
+	minVersion uint16
+}
+
+// CertFile with the PEM encoded certificate and private key files.
+func CertFile(certFile, keyFile string) Option {
+	return func(o *options) {
+		o.certFile = certFile
+		o.keyFile = keyFile
+	}
+}
+
+// CAFile with the PEM encoded CA certificates file which verifies the peers,
+// the system roots are used if it is not set.
+func CAFile(caFile string) Option {
+	return func(o *options) {
+		o.caFile = caFile
+	}
+}
+
+// PeerNames with the expected names of the peers, one of which must match
+// the DNS or URI SANs of the peer certificate, e.g. a SPIFFE ID.
+func PeerNames(names ...string) Option {
+	return func(o *options) {
+		o.peerNames = names
+	}
+}
+
+// ClientAuth with the policy of the server for the client certificates,
+// default tls.RequireAndVerifyClientCert with CAFile, otherwise tls.NoClientCert.
+func ClientAuth(auth tls.ClientAuthType) Option {
+	return func(o *options) {
+		o.clientAuth = auth
+	}
+}
+
+// MinVersion with the minimum TLS version, default TLS 1.2.
+func MinVersion(v uint16) Option {
+	return func(o *options) {
+		o.minVersion = v
+	}
+}
+
+// Loader loads the certificate and CA from files, and watches them for reloading.
+type Loader struct {
+	opts options
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	pool *x509.CertPool
+
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+}
+
+// NewLoader loads the files and watches the changes of them until closed.
+func NewLoader(opts ...Option) (*Loader, error) {
+	o := options{
+		clientAuth: -1,
+		minVersion: tls.VersionTLS12,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if (o.certFile == "") != (o.keyFile == "") {
+		return nil, errors.New("tls: both of the cert and key files must be set")
+	}
+	if o.clientAuth < 0 {
+		o.clientAuth = tls.NoClientCert
+		if o.caFile != "" {
+			o.clientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	l := &Loader{opts: o, done: make(chan struct{})}
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	if err := l.watch(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Loader) load() error {
+	var (
+		cert *tls.Certificate
+		pool *x509.CertPool
+	)
+	if l.opts.certFile != "" {
+		c, err := tls.LoadX509KeyPair(l.opts.certFile, l.opts.keyFile)
+		if err != nil {
+			return err
+		}
+		cert = &c
+	}
+	if l.opts.caFile != "" {
+		data, err := os.ReadFile(l.opts.caFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("tls: no valid certificates in %s", l.opts.caFile)
+		}
+	}
+	l.mu.Lock()
+	l.cert = cert
+	l.pool = pool
+	l.mu.Unlock()
+	return nil
+}
+
+// watch watches the directories of the files, since the files mounted from
+// Kubernetes secrets are replaced by renaming symlinks.
+func (l *Loader) watch() error {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	dirs := make(map[string]struct{})
+	for _, file := range []string{l.opts.certFile, l.opts.keyFile, l.opts.caFile} {
+		if file == "" {
+			continue
+		}
+		dir := filepath.Dir(file)
+		if _, ok := dirs[dir]; ok {
+			continue
+		}
+		dirs[dir] = struct{}{}
+		if err = fw.Add(dir); err != nil {
+			_ = fw.Close()
+			return err
+		}
+	}
+	l.watcher = fw
+	go func() {
+		for {
+			select {
+			case <-l.done:
+				return
+			case event, ok := <-fw.Events:
+				if !ok {
+					return
+				}
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				// the files may be partially written, the previous ones
+				// are kept until all of them are loaded successfully.
+				if err := l.load(); err != nil {
+					log.Warnf("[TLS] failed to reload certificates: %v", err)
+				}
+			case err, ok := <-fw.Errors:
+				if !ok {
+					return
+				}
+				log.Errorf("[TLS] failed to watch certificates: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Close stops watching the files.
+func (l *Loader) Close() error {
+	select {
+	case <-l.done:
+		return nil
+	default:
+		close(l.done)
+	}
+	return l.watcher.Close()
+}
+
+func (l *Loader) certificate() (*tls.Certificate, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.cert == nil {
+		return nil, errors.New("tls: no certificate is configured")
+	}
+	return l.cert, nil
+}
+
+func (l *Loader) roots() *x509.CertPool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.pool
+}
+
+// ServerConfig returns the TLS config of the servers, which presents the current
+// certificate and verifies the client certificates with the current CA.
+func (l *Loader) ServerConfig() *tls.Config {
+	getCertificate := func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return l.certificate()
+	}
+	return &tls.Config{
+		MinVersion:     l.opts.minVersion,
+		ClientAuth:     l.opts.clientAuth,
+		ClientCAs:      l.roots(),
+		GetCertificate: getCertificate,
+		// the ClientCAs can't be changed once the server has started, so the config
+		// with the current CA is returned for each handshake, the NextProtos are the
+		// ones served by the HTTP and gRPC servers since they are not inherited.
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c := &tls.Config{
+				MinVersion:     l.opts.minVersion,
+				NextProtos:     []string{"h2", "http/1.1"},
+				ClientAuth:     l.opts.clientAuth,
+				ClientCAs:      l.roots(),
+				GetCertificate: getCertificate,
+			}
+			if len(l.opts.peerNames) > 0 {
+				c.VerifyConnection = func(cs tls.ConnectionState) error {
+					// the names of the unverified certificates are not trusted.
+					if len(cs.VerifiedChains) == 0 {
+						return nil
+					}
+					return verifyPeerNames(cs.VerifiedChains[0][0], l.opts.peerNames)
+				}
+			}
+			return c, nil
+		},
+	}
+}
+
+// ClientConfig returns the TLS config of the clients, which presents the current
+// certificate and verifies the server certificate with the current CA, and the
+// certificate must match the dialed host, or one of the peer names instead if any.
+// The servers dialed by the IP addresses must be verified by the peer names, since
+// their addresses are not sent in the TLS handshake.
+func (l *Loader) ClientConfig() *tls.Config {
+	c := &tls.Config{
+		MinVersion: l.opts.minVersion,
+		// the RootCAs can't be changed once the client has been created,
+		// so the server certificate is verified with the current CA instead.
+		InsecureSkipVerify: true, //nolint:gosec
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return l.verify(cs.PeerCertificates, cs.ServerName)
+		},
+	}
+	if l.opts.certFile != "" {
+		c.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return l.certificate()
+		}
+	}
+	return c
+}
+
+// verify verifies the server certificates with the current CA, and the server name
+// or the peer names.
+func (l *Loader) verify(certs []*x509.Certificate, serverName string) error {
+	if len(certs) == 0 {
+		return errors.New("tls: no peer certificate")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         l.roots(),
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if len(l.opts.peerNames) == 0 {
+		if serverName == "" {
+			return errors.New("tls: the server name is required to verify the server certificate, or the peer names for the IP addresses")
+		}
+		opts.DNSName = serverName
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(opts); err != nil {
+		return err
+	}
+	if len(l.opts.peerNames) == 0 {
+		return nil
+	}
+	return verifyPeerNames(certs[0], l.opts.peerNames)
+}
+
+func verifyPeerNames(cert *x509.Certificate, names []string) error {
+	for _, name := range names {
+		for _, uri := range cert.URIs {
+			if uri.String() == name {
+				return nil
+			}
+		}
+		if cert.VerifyHostname(name) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("tls: peer certificate is not valid for any of %v", names)
+}
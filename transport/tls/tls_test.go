@@ -0,0 +1,274 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kratos ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes the certificate and key files issued by the CA for the names.
+func (ca *testCA) issue(t *testing.T, dir string, serial int64, dnsName, uri string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(uri)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{dnsName},
+		URIs:         []*url.URL{u},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	certFile, keyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return
+}
+
+func writeFile(t *testing.T, name string, data []byte) {
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func handshake(server, client *tls.Config) (*tls.ConnectionState, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	defer lis.Close()
+	type result struct {
+		state tls.ConnectionState
+		err   error
+	}
+	resc := make(chan result, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			resc <- result{err: err}
+			return
+		}
+		defer conn.Close()
+		sc := tls.Server(conn, server)
+		err = sc.Handshake()
+		if err == nil {
+			_, err = sc.Read(make([]byte, 1))
+		}
+		resc <- result{state: sc.ConnectionState(), err: err}
+	}()
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	cc := tls.Client(conn, client)
+	if err = cc.Handshake(); err == nil {
+		// the client certificate is verified by the server after the client
+		// has finished the handshake of TLS 1.3, so a byte is exchanged.
+		_, err = cc.Write([]byte{0})
+	}
+	res := <-resc
+	if err != nil {
+		return nil, err
+	}
+	return &res.state, res.err
+}
+
+func TestLoader(t *testing.T) {
+	ca := newTestCA(t)
+	serverDir, clientDir := t.TempDir(), t.TempDir()
+	caFile := filepath.Join(serverDir, "ca.crt")
+	writeFile(t, caFile, ca.pem)
+	serverCert, serverKey := ca.issue(t, serverDir, 2, "greeter.default.svc", "spiffe://kratos/greeter")
+	clientCert, clientKey := ca.issue(t, clientDir, 3, "client.default.svc", "spiffe://kratos/client")
+
+	server, err := NewLoader(CertFile(serverCert, serverKey), CAFile(caFile), PeerNames("spiffe://kratos/client"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	client, err := NewLoader(CertFile(clientCert, clientKey), CAFile(caFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	cc := client.ClientConfig()
+	cc.ServerName = "greeter.default.svc"
+	state, err := handshake(server.ServerConfig(), cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.VerifiedChains) == 0 || state.VerifiedChains[0][0].SerialNumber.Int64() != 3 {
+		t.Errorf("expected the verified client certificate, got %+v", state.VerifiedChains)
+	}
+
+	// the server name must match the server certificate.
+	cc.ServerName = "other.default.svc"
+	if _, err = handshake(server.ServerConfig(), cc); err == nil {
+		t.Error("expected the server name to be rejected")
+	}
+
+	// the IP address of the target isn't sent in SNI, so it must be verified by the peer names.
+	cc.ServerName = "127.0.0.1"
+	if _, err = handshake(server.ServerConfig(), cc); err == nil {
+		t.Error("expected the IP address to be rejected")
+	}
+
+	// the peer names of the server take precedence over the dialed host.
+	pinned, err := NewLoader(CertFile(clientCert, clientKey), CAFile(caFile), PeerNames("spiffe://kratos/greeter"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pinned.Close()
+	pc := pinned.ClientConfig()
+	pc.ServerName = "127.0.0.1"
+	if _, err = handshake(server.ServerConfig(), pc); err != nil {
+		t.Errorf("expected the server of the peer names to be accepted: %v", err)
+	}
+
+	// the client certificate is required.
+	anonymous, err := NewLoader(CAFile(caFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer anonymous.Close()
+	ac := anonymous.ClientConfig()
+	ac.ServerName = "greeter.default.svc"
+	if _, err = handshake(server.ServerConfig(), ac); err == nil {
+		t.Error("expected the client without certificate to be rejected")
+	}
+
+	// the client certificate must match the peer names.
+	strict, err := NewLoader(CertFile(serverCert, serverKey), CAFile(caFile), PeerNames("spiffe://kratos/admin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer strict.Close()
+	cc.ServerName = "greeter.default.svc"
+	if _, err = handshake(strict.ServerConfig(), cc); err == nil {
+		t.Error("expected the client certificate to be rejected")
+	}
+}
+
+func TestLoaderReload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := ca.issue(t, dir, 2, "greeter.default.svc", "spiffe://kratos/greeter")
+	l, err := NewLoader(CertFile(certFile, keyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	get := l.ServerConfig().GetCertificate
+	serial := func() int64 {
+		c, err := get(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, _ := x509.ParseCertificate(c.Certificate[0])
+		return cert.SerialNumber.Int64()
+	}
+	if serial() != 2 {
+		t.Fatalf("unexpected serial: %d", serial())
+	}
+	ca.issue(t, dir, 4, "greeter.default.svc", "spiffe://kratos/greeter")
+	for i := 0; i < 100 && serial() != 4; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if serial() != 4 {
+		t.Errorf("expected the certificate to be reloaded, got serial %d", serial())
+	}
+}
+
+func TestClientCAReload(t *testing.T) {
+	oldCA, newCA := newTestCA(t), newTestCA(t)
+	serverDir, clientDir := t.TempDir(), t.TempDir()
+	serverCert, serverKey := newCA.issue(t, serverDir, 2, "greeter.default.svc", "spiffe://kratos/greeter")
+	server, err := NewLoader(CertFile(serverCert, serverKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	caFile := filepath.Join(clientDir, "ca.crt")
+	writeFile(t, caFile, oldCA.pem)
+	client, err := NewLoader(CAFile(caFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// the config of the long-lived clients verifies with the rotated CA.
+	cc := client.ClientConfig()
+	cc.ServerName = "greeter.default.svc"
+	if _, err = handshake(server.ServerConfig(), cc); err == nil {
+		t.Fatal("expected the server of the other CA to be rejected")
+	}
+	writeFile(t, caFile, newCA.pem)
+	for i := 0; i < 100; i++ {
+		if _, err = handshake(server.ServerConfig(), cc); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Errorf("expected the server to be accepted by the rotated CA: %v", err)
+	}
+}
+
+func TestNewLoaderError(t *testing.T) {
+	if _, err := NewLoader(CertFile("tls.crt", "")); err == nil {
+		t.Error("expected an error without the key file")
+	}
+	if _, err := NewLoader(CAFile(filepath.Join(t.TempDir(), "ca.crt"))); err == nil {
+		t.Error("expected an error of the missing CA file")
+	}
+}
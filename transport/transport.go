@@ -10,6 +10,10 @@ import (
 	_ "github.com/go-kratos/kratos/v2/encoding/proto"
 	_ "github.com/go-kratos/kratos/v2/encoding/xml"
 	_ "github.com/go-kratos/kratos/v2/encoding/yaml"
+
+	// init compressors
+	_ "github.com/go-kratos/kratos/v2/encoding/deflate"
+	_ "github.com/go-kratos/kratos/v2/encoding/gzip"
 )
 
 // Server is transport server.
@@ -23,6 +27,11 @@ type Endpointer interface {
 	Endpoint() (*url.URL, error)
 }
 
+// MultiEndpointer is registry endpoints of a server which serves several protocols.
+type MultiEndpointer interface {
+	Endpoints() ([]*url.URL, error)
+}
+
 // Header is the storage medium used by a Header.
 type Header interface {
 	Get(key string) string